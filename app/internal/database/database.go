@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -41,14 +42,23 @@ func NewConnection(test int) (*Database, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = migrate(db)
+	if err != nil {
+		return nil, err
+	}
 
 	preparedQueries := map[string]string{
 		"INSERT_MOVES":       "INSERT INTO moves (game_id, move_data) VALUES (?, ?)",
-		"INSERT_GAME":        "INSERT INTO games (chessdotcom_id, playerIsWhite) VALUES (?, ?) RETURNING id",
+		"INSERT_MOVES_TREE":  "INSERT INTO moves (game_id, move_data, tree) VALUES (?, ?, ?)",
+		"INSERT_GAME":        "INSERT INTO games (chessdotcom_id, playerIsWhite, fen, variant) VALUES (?, ?, ?, ?) RETURNING id",
 		"GET_LATEST_GAME_ID": "SELECT id FROM games WHERE chessdotcom_id = ? ORDER BY created_at DESC LIMIT 1",
-		"GET_LATEST_MOVES":   "SELECT id, move_data, scores, depth FROM moves WHERE game_id = ? ORDER BY created_at DESC LIMIT 1",
-		"GET_GAMES":          "SELECT id, created_at, chessdotcom_id, playerIsWhite FROM games",
-		"UPDATE_EVAL":        "UPDATE moves SET scores = ?, depth = ? WHERE id = ?",
+		"GET_LATEST_MOVES":   "SELECT id, move_data, scores, depth, best_lines, wdl FROM moves WHERE game_id = ? ORDER BY created_at DESC, id DESC LIMIT 1",
+		"GET_GAMES":          "SELECT id, created_at, chessdotcom_id, playerIsWhite, event, site, date, round, white, black, result, fen, variant FROM games",
+		"GET_GAME":           "SELECT id, created_at, chessdotcom_id, playerIsWhite, event, site, date, round, white, black, result, fen, variant FROM games WHERE id = ?",
+		"INSERT_PGN_GAME":    "INSERT INTO games (playerIsWhite, event, site, date, round, white, black, result, fen, variant) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+		"UPDATE_EVAL":        "UPDATE moves SET scores = ?, depth = ?, best_lines = ?, wdl = ? WHERE id = ?",
+		"GET_LATEST_TREE":    "SELECT id, move_data, tree FROM moves WHERE game_id = ? ORDER BY created_at DESC, id DESC LIMIT 1",
+		"UPDATE_TREE":        "UPDATE moves SET tree = ? WHERE id = ?",
 	}
 
 	return &Database{
@@ -58,6 +68,28 @@ func NewConnection(test int) (*Database, error) {
 	}, nil
 }
 
+// Columns added to each table after it was first created
+var addedColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"games", []string{"event", "site", "date", "round", "white", "black", "result", "fen", "variant"}},
+	{"moves", []string{"best_lines", "tree", "wdl"}},
+}
+
+// migrate adds any missing columns to tables created by an older schema
+func migrate(db *sql.DB) error {
+	for _, added := range addedColumns {
+		for _, column := range added.columns {
+			_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", added.table, column))
+			if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Close closes the connection to the SQLite3 database
 func (d Database) Close() {
 	d.db.Close()
@@ -1,10 +1,26 @@
 package database
 
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
 type Game struct {
 	ID            int
 	CreatedAt     string
 	ChessdotcomID string
 	PlayerIsWhite bool
+	Event         string
+	Site          string
+	Date          string
+	Round         string
+	White         string
+	Black         string
+	Result        string
+	FEN           string // starting position, empty for the initial position
+	Variant       game.Variant
 }
 
 // GetGames returns all games from the database
@@ -16,12 +32,125 @@ func (d Database) GetGames() ([]Game, error) {
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var game Game
-		err := rows.Scan(&game.ID, &game.CreatedAt, &game.ChessdotcomID, &game.PlayerIsWhite)
+		game, err := scanGame(rows)
 		if err != nil {
 			return nil, err
 		}
-		games = append(games, game)
+		games = append(games, *game)
 	}
 	return games, nil
 }
+
+// GetGameByID returns the game with the given id
+func (d Database) GetGameByID(id int) (*Game, error) {
+	return scanGame(d.db.QueryRow(d.queries["GET_GAME"], id))
+}
+
+// Scans a row selected with the columns of GET_GAMES into a Game
+func scanGame(row interface{ Scan(dest ...any) error }) (*Game, error) {
+	var g Game
+	var chessdotcomID, event, site, date, round, white, black, result, fen, variant sql.NullString
+	err := row.Scan(
+		&g.ID,
+		&g.CreatedAt,
+		&chessdotcomID,
+		&g.PlayerIsWhite,
+		&event,
+		&site,
+		&date,
+		&round,
+		&white,
+		&black,
+		&result,
+		&fen,
+		&variant,
+	)
+	if err != nil {
+		return nil, err
+	}
+	g.ChessdotcomID = chessdotcomID.String
+	g.Event = event.String
+	g.Site = site.String
+	g.Date = date.String
+	g.Round = round.String
+	g.White = white.String
+	g.Black = black.String
+	g.Result = result.String
+	g.FEN = fen.String
+	g.Variant, err = game.ParseVariant(variant.String)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// InsertPGN inserts every game in a PGN string into the database
+//
+// player is the name of the user in the White and Black tags, games they played
+// as black are shown from black's side. Returns the ids of the inserted games
+func (d Database) InsertPGN(pgn, player string) ([]int, error) {
+	pgnGames, err := game.ReadPGN(pgn)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	ids := []int{}
+	for _, pgnGame := range pgnGames {
+		var gameID int
+		tags := pgnGame.Tags
+		err = tx.QueryRow(
+			d.queries["INSERT_PGN_GAME"],
+			playerIsWhite(tags, player),
+			nullString(tags["Event"]),
+			nullString(tags["Site"]),
+			nullString(tags["Date"]),
+			nullString(tags["Round"]),
+			nullString(tags["White"]),
+			nullString(tags["Black"]),
+			nullString(pgnGame.Result),
+			nullString(tags["FEN"]),
+			variantString(pgnGame.Game.Variant),
+		).Scan(&gameID)
+		if err != nil {
+			return nil, err
+		}
+		// The tree keeps the variations, comments and NAGs of the movetext
+		tree, err := pgnGame.GameTree()
+		if err != nil {
+			return nil, err
+		}
+		movetext, err := tree.Movetext()
+		if err != nil {
+			return nil, err
+		}
+		moves := game.ConvertMovesToLongAlgebraicNotation(pgnGame.Game.MoveHistory)
+		_, err = tx.Exec(d.queries["INSERT_MOVES_TREE"], gameID, strings.Join(moves, " "), movetext)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, gameID)
+	}
+	return ids, tx.Commit()
+}
+
+// Returns false if the player is named in the Black tag, otherwise true
+func playerIsWhite(tags map[string]string, player string) bool {
+	return player == "" || !strings.EqualFold(strings.TrimSpace(tags["Black"]), strings.TrimSpace(player))
+}
+
+// Converts an empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Converts a variant to the name stored in the games table, NULL for standard chess
+func variantString(variant game.Variant) sql.NullString {
+	if variant == game.Standard {
+		return sql.NullString{}
+	}
+	return nullString(variant.String())
+}
@@ -37,3 +37,80 @@ func TestGetGames(t *testing.T) {
 
 	db.Close()
 }
+
+func TestInsertPGN(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(6)
+	if err != nil {
+		t.Error(err)
+	}
+	pgn := `[Event "Casual Game"]
+[Site "Berlin GER"]
+[Date "1852.??.??"]
+[Round "?"]
+[White "Adolf Anderssen"]
+[Black "Jean Dufresne"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. b4 Bxb4 5. c3 Ba5 6. d4 exd4 7. O-O d3
+8. Qb3 Qf6 9. e5 Qg6 10. Re1 Nge7 11. Ba3 b5 12. Qxb5 Rb8 13. Qa4 Bb6
+14. Nbd2 Bb7 15. Ne4 Qf5 16. Bxd3 Qh5 17. Nf6+ gxf6 18. exf6 Rg8 19. Rad1 Qxf3
+20. Rxe7+ Nxe7 21. Qxd7+ Kxd7 22. Bf5+ Ke8 23. Bd7+ Kf8 24. Bxe7# 1-0
+
+[Event "Puzzle"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+
+1. e4 *
+`
+	ids, err := db.InsertPGN(pgn, "Jean Dufresne")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(ids))
+	}
+	games, err := db.GetGames()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(games))
+	}
+	if games[0].White != "Adolf Anderssen" || games[0].Black != "Jean Dufresne" {
+		t.Errorf("Expected Adolf Anderssen vs Jean Dufresne, got %s vs %s", games[0].White, games[0].Black)
+	}
+	if games[0].Result != "1-0" || games[0].Date != "1852.??.??" || games[0].FEN != "" {
+		t.Errorf("Unexpected game headers %v", games[0])
+	}
+	if games[1].FEN != "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1" || games[1].Result != "*" {
+		t.Errorf("Unexpected game headers %v", games[1])
+	}
+	// the player's games are shown from their side
+	if games[0].PlayerIsWhite || !games[1].PlayerIsWhite {
+		t.Errorf("Expected the player to be black then white, got %v and %v", games[0].PlayerIsWhite, games[1].PlayerIsWhite)
+	}
+	moves, err := db.GetMovesByID(ids[0])
+	if err != nil {
+		t.Error(err)
+	}
+	if len(moves.Moves) != 47 {
+		t.Errorf("Expected 47 moves, got %d", len(moves.Moves))
+	}
+	if moves.Moves[46] != "Ba3xe7#" {
+		t.Errorf("Expected final move Ba3xe7#, got %s", moves.Moves[46])
+	}
+
+	// Invalid PGN inserts nothing
+	_, err = db.InsertPGN(pgn+"\n1. e4 e5 2. Ke3 *", "")
+	if err == nil {
+		t.Error("Expected error inserting invalid PGN")
+	}
+	games, _ = db.GetGames()
+	if len(games) != 2 {
+		t.Errorf("Expected 2 games, got %d", len(games))
+	}
+	db.Close()
+}
@@ -15,16 +15,34 @@ var (
 )
 
 type Move struct {
-	ID     int
-	Moves  []string
-	Scores []string
-	Depth  int
+	ID        int
+	Moves     []string
+	Scores    []string
+	Depth     int
+	BestLines [][]string // engine's best line in UCI notation for each position
+	WDL       [][3]int   // chances of a win, draw and loss for white per mille for each position, zero if not sent
 }
 
 // InsertMoves inserts a list of moves into the database
 func (d Database) InsertMoves(moves []string, chessdotcomID string, playerIsWhite bool) error {
+	return d.InsertMovesFromFEN(moves, "", chessdotcomID, playerIsWhite)
+}
+
+// InsertMovesFromFEN inserts a list of moves played from the position described
+// by a FEN string, such as the start of a Chess960 game
+//
+// An empty fen starts from the initial position
+func (d Database) InsertMovesFromFEN(moves []string, fen, chessdotcomID string, playerIsWhite bool) error {
+	return d.InsertVariantMoves(moves, game.Standard, fen, chessdotcomID, playerIsWhite)
+}
+
+// InsertVariantMoves inserts a list of moves of a game played by the rules of a variant
+// from the position described by a FEN string
+//
+// An empty fen starts from the initial position
+func (d Database) InsertVariantMoves(moves []string, variant game.Variant, fen, chessdotcomID string, playerIsWhite bool) error {
 	chessdotcomID_NullString := sql.NullString{String: chessdotcomID, Valid: chessdotcomID != ""}
-	standardizedMoves, err := standardizeMoves(moves)
+	standardizedMoves, err := standardizeMoves(moves, variant, fen)
 	if err != nil {
 		return err
 	}
@@ -34,7 +52,7 @@ func (d Database) InsertMoves(moves []string, chessdotcomID string, playerIsWhit
 	err = d.db.QueryRow(d.queries["GET_LATEST_GAME_ID"], chessdotcomID_NullString).Scan(&gameID)
 	if err == sql.ErrNoRows {
 		// If no game with the given chess.com id exists, create a new game
-		err = d.db.QueryRow(d.queries["INSERT_GAME"], chessdotcomID_NullString, playerIsWhite).Scan(&gameID)
+		err = d.db.QueryRow(d.queries["INSERT_GAME"], chessdotcomID_NullString, playerIsWhite, nullString(fen), variantString(variant)).Scan(&gameID)
 		if err != nil {
 			return err
 		}
@@ -63,7 +81,9 @@ func (d Database) GetMovesByID(id int) (*Move, error) {
 	var scores sql.NullString
 	var moves_id int
 	var depth sql.NullInt64
-	err := d.db.QueryRow(d.queries["GET_LATEST_MOVES"], id).Scan(&moves_id, &moves, &scores, &depth)
+	var bestLines sql.NullString
+	var wdl sql.NullString
+	err := d.db.QueryRow(d.queries["GET_LATEST_MOVES"], id).Scan(&moves_id, &moves, &scores, &depth, &bestLines, &wdl)
 	if err != nil {
 		return nil, ErrNoMoves
 	}
@@ -75,11 +95,25 @@ func (d Database) GetMovesByID(id int) (*Move, error) {
 	if depth.Valid {
 		depthOut = int(depth.Int64)
 	}
+	bestLinesOut := [][]string{}
+	if bestLines.Valid {
+		for _, line := range strings.Split(bestLines.String, ";") {
+			bestLinesOut = append(bestLinesOut, strings.Fields(line))
+		}
+	}
+	wdlOut := [][3]int{}
+	if wdl.Valid {
+		for _, wdlStr := range strings.Fields(wdl.String) {
+			wdlOut = append(wdlOut, eval.ParseWDLStr(wdlStr))
+		}
+	}
 	return &Move{
-		ID:     moves_id,
-		Moves:  strings.Split(moves, " "),
-		Scores: scoresOut,
-		Depth:  depthOut,
+		ID:        moves_id,
+		Moves:     strings.Split(moves, " "),
+		Scores:    scoresOut,
+		Depth:     depthOut,
+		BestLines: bestLinesOut,
+		WDL:       wdlOut,
 	}, nil
 }
 
@@ -87,7 +121,7 @@ func (d Database) GetMovesByID(id int) (*Move, error) {
 //
 // Expected input: ["1", "e4", "e5", "2", "Nf3", "Nc6", ...]
 // Expected output: "e4 e5 Nf3 Nc6 ..."
-func standardizeMoves(moves []string) (string, error) {
+func standardizeMoves(moves []string, variant game.Variant, fen string) (string, error) {
 	// remove turn numbers
 	standardizedMoves := []string{}
 	for i, move := range moves {
@@ -95,21 +129,49 @@ func standardizeMoves(moves []string) (string, error) {
 			standardizedMoves = append(standardizedMoves, move)
 		}
 	}
-	standardizedMoves, err := game.ConvertNotation(standardizedMoves)
+	g, err := game.NewVariantGame(variant, fen)
 	if err != nil {
 		return "", err
 	}
-	moveString := strings.Join(standardizedMoves, " ")
+	err = g.Moves(standardizedMoves)
+	if err != nil {
+		return "", err
+	}
+	moveString := strings.Join(game.ConvertMovesToLongAlgebraicNotation(g.MoveHistory), " ")
 	return moveString, nil
 }
 
+// EvalAt returns the stored eval of the position with the given index,
+// nil if the engine couldn't evaluate the position
+func (m *Move) EvalAt(i int) *eval.MoveEval {
+	if m.Scores[i] == "-" {
+		return nil
+	}
+	e := eval.ParseScoreStr(m.Scores[i])
+	if i < len(m.WDL) {
+		e.WDL = m.WDL[i]
+	}
+	return e
+}
+
 // UpdateEval updates the evaluation of a move in the database
+//
+// The best line of each position is stored alongside its score,
+// lines are separated by ";" and their moves by spaces. WDL is stored as
+// "win/draw/loss" for each position, "-" if the engine didn't send it.
+// Positions the engine couldn't evaluate are stored with a score of "-",
+// so the scores stay aligned with the positions
 func (d Database) UpdateEval(moveID int, evalss [][]*eval.MoveEval) error {
 	scores := []string{}
+	bestLines := []string{}
+	wdls := []string{}
 	depth := 0
 	for _, evals := range evalss {
 		e := eval.GetEvalNum(evals, 1)
 		if e == nil {
+			scores = append(scores, "-")
+			bestLines = append(bestLines, "")
+			wdls = append(wdls, "-")
 			continue
 		}
 		if e.Depth > depth {
@@ -120,8 +182,16 @@ func (d Database) UpdateEval(moveID int, evalss [][]*eval.MoveEval) error {
 		} else {
 			scores = append(scores, fmt.Sprintf("%d", e.Score))
 		}
+		bestLines = append(bestLines, strings.Join(e.BestLine, " "))
+		if e.WDL == [3]int{} {
+			wdls = append(wdls, "-")
+		} else {
+			wdls = append(wdls, fmt.Sprintf("%d/%d/%d", e.WDL[0], e.WDL[1], e.WDL[2]))
+		}
 	}
 	scoresStr := strings.Join(scores, " ")
-	_, err := d.db.Exec(d.queries["UPDATE_EVAL"], scoresStr, depth, moveID)
+	bestLinesStr := strings.Join(bestLines, ";")
+	wdlStr := strings.Join(wdls, " ")
+	_, err := d.db.Exec(d.queries["UPDATE_EVAL"], scoresStr, depth, bestLinesStr, wdlStr, moveID)
 	return err
 }
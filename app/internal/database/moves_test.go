@@ -1,6 +1,7 @@
 package database
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
@@ -86,6 +87,7 @@ func TestUpdateEval(t *testing.T) {
 			Mate:   false,
 			MateIn: 0,
 			PVnum:  1,
+			WDL:    [3]int{95, 880, 25},
 		}},
 	}
 	err = db.UpdateEval(1, evals)
@@ -102,5 +104,89 @@ func TestUpdateEval(t *testing.T) {
 	if moves.Depth != 22 {
 		t.Errorf("Expected depth 22, got %d", moves.Depth)
 	}
+	expectedWDL := [][3]int{{}, {}, {95, 880, 25}}
+	if !reflect.DeepEqual(moves.WDL, expectedWDL) {
+		t.Errorf("Expected WDL %v, got %v", expectedWDL, moves.WDL)
+	}
+	db.Close()
+}
+
+func TestUpdateEvalMissingPly(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.InsertMoves([]string{"1", "e4", "e5"}, "missing-ply", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the engine failed to evaluate the position after e4
+	evals := [][]*eval.MoveEval{
+		{{Depth: 20, Score: 30, PVnum: 1, BestLine: []string{"e2e4"}}},
+		nil,
+		{{Depth: 20, Score: 40, PVnum: 1, BestLine: []string{"g1f3"}, WDL: [3]int{95, 880, 25}}},
+	}
+	moves, err := db.GetMovesByChessdotcomID("missing-ply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.UpdateEval(moves.ID, evals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves, err = db.GetMovesByChessdotcomID("missing-ply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves.Scores) != 3 || len(moves.BestLines) != 3 || len(moves.WDL) != 3 {
+		t.Fatalf("Expected an entry for each position, got scores %v, best lines %v and WDL %v", moves.Scores, moves.BestLines, moves.WDL)
+	}
+	if e := moves.EvalAt(1); e != nil {
+		t.Errorf("Expected no eval for the position after e4, got %v", e)
+	}
+	// the evals after the missing one stay with their positions
+	e := moves.EvalAt(2)
+	if e == nil || e.Score != 40 || e.WDL != [3]int{95, 880, 25} {
+		t.Errorf("Expected the eval of the position after e5, got %v", e)
+	}
+	if !reflect.DeepEqual(moves.BestLines[2], []string{"g1f3"}) {
+		t.Errorf("Expected the best line after e5 g1f3, got %v", moves.BestLines[2])
+	}
+}
+
+func TestInsertMovesFromFEN(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(3)
+	if err != nil {
+		t.Error(err)
+	}
+	// Chess960 position with the king on b1 and rooks on a1 and h1
+	fen := "rk5r/pppppppp/8/8/8/8/PPPPPPPP/RK5R w KQkq - 0 1"
+	movesToInsert := []string{"1", "O-O", "O-O-O"}
+	expectedMoves := []string{"O-O", "O-O-O"}
+	err = db.InsertMovesFromFEN(movesToInsert, fen, "960", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves, err := db.GetMovesByChessdotcomID("960")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves.Moves) != len(expectedMoves) {
+		t.Fatalf("Expected %d moves, got %d", len(expectedMoves), len(moves.Moves))
+	}
+	for i := range moves.Moves {
+		if moves.Moves[i] != expectedMoves[i] {
+			t.Errorf("Expected move %s, got %s", expectedMoves[i], moves.Moves[i])
+		}
+	}
 	db.Close()
 }
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
+// ExportPGN returns the game with the given id in PGN
+//
+// If the game has been evaluated each move is annotated with an [%eval] comment,
+// inaccuracies, mistakes and blunders are marked with NAGs, and the engine's best
+// line is added as a variation to mistakes and blunders
+func (d Database) ExportPGN(id int) (string, error) {
+	gameFromDB, err := d.GetGameByID(id)
+	if err != nil {
+		return "", err
+	}
+	tags := map[string]string{
+		"Event": gameFromDB.Event,
+		"Site":  gameFromDB.Site,
+		"Date":  gameFromDB.Date,
+		"Round": gameFromDB.Round,
+		"White": gameFromDB.White,
+		"Black": gameFromDB.Black,
+	}
+	g, err := game.NewVariantGame(gameFromDB.Variant, gameFromDB.FEN)
+	if err != nil {
+		return "", err
+	}
+	if gameFromDB.FEN != "" {
+		tags["SetUp"] = "1"
+		tags["FEN"] = gameFromDB.FEN
+	}
+	switch {
+	case g.Variant != game.Standard:
+		tags["Variant"] = g.Variant.String()
+	case g.Chess960:
+		tags["Variant"] = "Chess960"
+	}
+	pgnGame := &game.PGNGame{
+		Tags:   tags,
+		Result: gameFromDB.Result,
+	}
+
+	tree, err := d.GetGameTree(id)
+	if err != nil {
+		return "", err
+	}
+	mainline := tree.Mainline()
+	movesFromDB, err := d.GetMovesByID(id)
+	if err != nil && err != ErrNoMoves {
+		return "", err
+	}
+	// Scores are only usable if there is one for every position
+	if err == nil && len(movesFromDB.Scores) == len(mainline)+1 {
+		for i, node := range mainline {
+			err = annotateNode(tree, node, movesFromDB, i)
+			if err != nil {
+				return "", fmt.Errorf("move %d: %w", i+1, err)
+			}
+		}
+	}
+	pgnGame.Moves = tree.PGNMoves()
+	// Games without a recorded result are finished if they end in checkmate or stalemate
+	if pgnGame.Result == "" || pgnGame.Result == "*" {
+		final := tree.Root
+		if len(mainline) > 0 {
+			final = mainline[len(mainline)-1]
+		}
+		g, err = tree.GameAt(final)
+		if err != nil {
+			return "", err
+		}
+		pgnGame.Result = g.Result()
+	}
+	return pgnGame.PGN()
+}
+
+// Adds the eval of the position after the mainline move, the classification of
+// the move and, for mistakes and blunders, the engine's best line instead of the
+// move as a variation
+//
+// i is the index of the move in the mainline
+func annotateNode(tree *game.GameTree, node *game.Node, movesFromDB *Move, i int) error {
+	previous, err := tree.GameAt(node.Parent)
+	if err != nil {
+		return err
+	}
+	whiteMoved := previous.Turn == "white"
+	before := movesFromDB.EvalAt(i)
+	after := movesFromDB.EvalAt(i + 1)
+	if after == nil {
+		return nil
+	}
+	if comment := evalComment(after, !whiteMoved); comment != "" {
+		node.Comments = append(node.Comments, comment)
+	}
+	if before == nil {
+		return nil
+	}
+	classification := eval.ClassifyMove(before, after, whiteMoved)
+	if classification == eval.Good {
+		return nil
+	}
+	node.NAGs = append(node.NAGs, classification.NAG())
+	if classification < eval.Mistake || i >= len(movesFromDB.BestLines) {
+		return nil
+	}
+	// The line is cut short at the first move that can't be played
+	bestLine := movesFromDB.BestLines[i]
+	moves, _, _ := previous.UCILine(bestLine)
+	played, _ := node.Move.UCInotation()
+	if len(moves) == 0 || bestLine[0] == played {
+		return nil
+	}
+	_, err = tree.AddLine(node.Parent, moves)
+	return err
+}
+
+// Returns the eval comment for a position e.g. [%eval 0.35] or [%eval #-3]
+// from white's perspective
+//
+// Returns an empty string if the side to move is checkmated
+func evalComment(e *eval.MoveEval, whiteToMove bool) string {
+	if !e.Mate {
+		return fmt.Sprintf("[%%eval %.2f]", float64(e.Score)/100)
+	}
+	if e.MateIn == 0 {
+		return ""
+	}
+	mateIn := e.MateIn
+	if !whiteToMove {
+		mateIn = -mateIn
+	}
+	return fmt.Sprintf("[%%eval #%d]", mateIn)
+}
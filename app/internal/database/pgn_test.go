@@ -0,0 +1,135 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
+func TestExportPGN(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(8)
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	pgn := `[Event "Casual Game"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0`
+	ids, err := db.InsertPGN(pgn, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Without evals the moves are exported as they were imported
+	exported, err := db.ExportPGN(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[Event "Casual Game"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+`
+	if exported != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, exported)
+	}
+
+	// With evals
+	moves, err := db.GetMovesByID(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	evals := [][]*eval.MoveEval{
+		{{Score: 30, PVnum: 1, BestLine: []string{"e2e4"}}},
+		{{Score: 30, PVnum: 1, BestLine: []string{"e7e5"}}},
+		{{Score: 30, PVnum: 1, BestLine: []string{"g1f3"}}},
+		{{Score: 0, PVnum: 1, BestLine: []string{"b8c6"}}},
+		{{Score: 20, PVnum: 1, BestLine: []string{"f1c4"}}},
+		{{Score: 10, PVnum: 1, BestLine: []string{"g7g6", "h5f3", "g8f6"}}},
+		{{Mate: true, MateIn: 1, PVnum: 1, BestLine: []string{"h5f7"}}},
+		{{Mate: true, MateIn: 0, PVnum: 1}},
+	}
+	err = db.UpdateEval(moves.ID, evals)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exported, err = db.ExportPGN(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedMovetext := "1. e4 {[%eval 0.30]} 1... e5 {[%eval 0.30]} 2. Qh5 {[%eval 0.00]} 2... Nc6\n" +
+		"{[%eval 0.20]} 3. Bc4 {[%eval 0.10]} 3... Nf6 $4 {[%eval #1]} (3... g6 4. Qf3\n" +
+		"Nf6) 4. Qxf7# 1-0\n"
+	if !strings.HasSuffix(exported, expectedMovetext) {
+		t.Errorf("Expected movetext:\n%s\ngot:\n%s", expectedMovetext, exported)
+	}
+	// The annotated game can be read back in
+	games, err := game.ReadPGN(exported)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(games[0].Moves[5].Variations) != 1 {
+		t.Errorf("Expected best line variation, got %v", games[0].Moves[5].Variations)
+	}
+
+	// Games without a result get one from the final position
+	ids, err = db.InsertPGN("1. f3 e5 2. g4 Qh4 *", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exported, err = db.ExportPGN(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(exported, "[Result \"0-1\"]") || !strings.HasSuffix(exported, "2. g4 Qh4# 0-1\n") {
+		t.Errorf("Expected checkmate result, got:\n%s", exported)
+	}
+}
+
+func TestExportVariantPGN(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(9)
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	// exd5 removes both pawns and Qxd2 explodes the white king
+	pgn := `[Variant "Atomic"]
+
+1. e4 d5 2. exd5 Qxd2 0-1`
+	ids, err := db.InsertPGN(pgn, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gameFromDB, err := db.GetGameByID(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gameFromDB.Variant != game.Atomic {
+		t.Errorf("Expected variant %v, got %v", game.Atomic, gameFromDB.Variant)
+	}
+	exported, err := db.ExportPGN(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(exported, "[Variant \"Atomic\"]") || !strings.Contains(exported, "1. e4 d5 2. exd5 Qxd2 0-1") {
+		t.Errorf("Expected the atomic game to be exported, got:\n%s", exported)
+	}
+}
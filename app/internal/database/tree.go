@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
+// GetGameTree returns the latest moves of the game with the given id
+// with their variations, comments and NAGs
+//
+// Moves stored without a tree only have their mainline
+func (d Database) GetGameTree(id int) (*game.GameTree, error) {
+	gameFromDB, err := d.GetGameByID(id)
+	if err != nil {
+		return nil, err
+	}
+	start, err := game.NewVariantGame(gameFromDB.Variant, gameFromDB.FEN)
+	if err != nil {
+		return nil, err
+	}
+	var movesID int
+	var moves string
+	var tree sql.NullString
+	err = d.db.QueryRow(d.queries["GET_LATEST_TREE"], id).Scan(&movesID, &moves, &tree)
+	if err == sql.ErrNoRows {
+		return game.NewGameTree(start), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if tree.Valid {
+		return game.ParseMovetext(start, tree.String)
+	}
+	return mainlineTree(start, strings.Fields(moves))
+}
+
+// SaveGameTree stores the variations, comments and NAGs of the game with the given id
+//
+// If the mainline has changed it is inserted as new moves, as the evals of
+// the previous moves no longer apply
+func (d Database) SaveGameTree(id int, tree *game.GameTree) error {
+	movetext, err := tree.Movetext()
+	if err != nil {
+		return err
+	}
+	mainline := []game.Move{}
+	for _, node := range tree.Mainline() {
+		mainline = append(mainline, node.Move)
+	}
+	moves := strings.Join(game.ConvertMovesToLongAlgebraicNotation(mainline), " ")
+
+	var movesID int
+	var previous string
+	var previousTree sql.NullString
+	err = d.db.QueryRow(d.queries["GET_LATEST_TREE"], id).Scan(&movesID, &previous, &previousTree)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && previous == moves {
+		_, err = d.db.Exec(d.queries["UPDATE_TREE"], movetext, movesID)
+		return err
+	}
+	_, err = d.db.Exec(d.queries["INSERT_MOVES_TREE"], id, moves, movetext)
+	return err
+}
+
+// Creates a tree with only a mainline from moves in the format used in the database
+func mainlineTree(start *game.Game, moveStrs []string) (*game.GameTree, error) {
+	g := start.Clone()
+	moves := []game.Move{}
+	for i, moveStr := range moveStrs {
+		move, err := g.Move(moveStr)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		moves = append(moves, move)
+	}
+	tree := game.NewGameTree(start)
+	_, err := tree.AddLine(tree.Root, moves)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
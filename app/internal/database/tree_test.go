@@ -0,0 +1,102 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
+func TestGameTree(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	db, err := NewConnection(10)
+	if err != nil {
+		t.Error(err)
+	}
+	defer db.Close()
+	ids, err := db.InsertPGN("1. e4 {King's pawn} (1. d4 d5) 1... e5 2. Nf3 *", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tree, err := db.GetGameTree(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tree.Mainline()) != 3 || len(tree.Root.Children) != 2 {
+		t.Fatalf("Expected 3 mainline moves and a variation, got %d and %d", len(tree.Mainline()), len(tree.Root.Children))
+	}
+	if tree.Mainline()[0].Comments[0] != "King's pawn" {
+		t.Errorf("Expected the comment to be kept, got %v", tree.Mainline()[0].Comments)
+	}
+	before, err := db.GetMovesByID(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Adding a variation keeps the moves and their evals
+	e5 := tree.Mainline()[1]
+	_, err = tree.AddMove(e5.Parent, game.Move{FromFile: 'c', FromRank: 7, ToFile: 'c', ToRank: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = db.SaveGameTree(ids[0], tree)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after, err := db.GetMovesByID(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if after.ID != before.ID {
+		t.Errorf("Expected the moves to be updated, got new moves %d", after.ID)
+	}
+	exported, err := db.ExportPGN(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "1. e4 {King's pawn} (1. d4 d5) 1... e5 (1... c5) 2. Nf3 *\n"
+	if !strings.HasSuffix(exported, expected) {
+		t.Errorf("Expected movetext %s, got:\n%s", expected, exported)
+	}
+
+	// Promoting a variation changes the moves
+	tree.Root.Children[1].PromoteToMainline()
+	err = db.SaveGameTree(ids[0], tree)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after, err = db.GetMovesByID(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if after.ID == before.ID || strings.Join(after.Moves, " ") != "d2d4 d7d5" {
+		t.Errorf("Expected new moves d2d4 d7d5, got %v", after.Moves)
+	}
+	tree, err = db.GetGameTree(ids[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tree.Root.Children) != 2 || len(tree.Root.Children[1].Line()) != 3 {
+		t.Errorf("Expected 1. e4 to be kept as a variation")
+	}
+
+	// Moves inserted without a tree only have their mainline
+	err = db.InsertMoves([]string{"1", "e4", "e5"}, "tree", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	games, err := db.GetGames()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tree, err = db.GetGameTree(games[len(games)-1].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tree.Mainline()) != 2 || len(tree.Root.Children) != 1 {
+		t.Errorf("Expected a mainline of 2 moves, got %d", len(tree.Mainline()))
+	}
+}
@@ -0,0 +1,111 @@
+package eval
+
+// How much a move worsened the position for the player who made it
+type Classification int
+
+const (
+	Good Classification = iota
+	Inaccuracy
+	Mistake
+	Blunder
+)
+
+// Centipawn losses at which a move is classified as worse
+const (
+	InaccuracyThreshold = 50
+	MistakeThreshold    = 100
+	BlunderThreshold    = 300
+)
+
+// Losses in the expected points of the player who moved, per mille, at which a move
+// is classified as worse when the engine sent WDL for both positions
+const (
+	InaccuracyWDLThreshold = 50
+	MistakeWDLThreshold    = 100
+	BlunderWDLThreshold    = 150
+)
+
+// Scores are clamped to this many centipawns so that a lost position
+// getting more lost isn't punished, and a forced mate counts as the maximum
+const MaxScore = 1000
+
+// Returns the score of the eval in centipawns from white's perspective
+//
+// Centipawn scores are already from white's perspective, mates are from
+// the perspective of the side to move
+func WhiteScore(e *MoveEval, whiteToMove bool) int {
+	if e.Mate {
+		score := MaxScore
+		if e.MateIn <= 0 {
+			score = -MaxScore
+		}
+		if !whiteToMove {
+			score = -score
+		}
+		return score
+	}
+	return min(MaxScore, max(-MaxScore, e.Score))
+}
+
+// Returns white's expected points per mille from the WDL of the eval, where
+// a win is worth 1000 and a draw 500
+//
+// Returns false if the engine didn't send WDL
+func WhiteExpectation(e *MoveEval) (int, bool) {
+	if e.WDL == [3]int{} {
+		return 0, false
+	}
+	return e.WDL[0] + e.WDL[1]/2, true
+}
+
+// Classifies a move by comparing the eval before and after it was played
+//
+// The chances of winning and drawing are compared if the engine sent WDL, as a
+// centipawn loss means little in a position that is won or drawn either way
+func ClassifyMove(before, after *MoveEval, whiteMoved bool) Classification {
+	if expectedBefore, ok := WhiteExpectation(before); ok {
+		if expectedAfter, ok := WhiteExpectation(after); ok {
+			loss := expectedBefore - expectedAfter
+			if !whiteMoved {
+				loss = -loss
+			}
+			switch {
+			case loss >= BlunderWDLThreshold:
+				return Blunder
+			case loss >= MistakeWDLThreshold:
+				return Mistake
+			case loss >= InaccuracyWDLThreshold:
+				return Inaccuracy
+			}
+			return Good
+		}
+	}
+	loss := WhiteScore(before, whiteMoved) - WhiteScore(after, !whiteMoved)
+	if !whiteMoved {
+		loss = -loss
+	}
+	switch {
+	case loss >= BlunderThreshold:
+		return Blunder
+	case loss >= MistakeThreshold:
+		return Mistake
+	case loss >= InaccuracyThreshold:
+		return Inaccuracy
+	}
+	return Good
+}
+
+// Returns the numeric annotation glyph used in PGN for the classification
+//
+// Returns 0 for good moves
+func (c Classification) NAG() int {
+	switch c {
+	case Inaccuracy:
+		return 6 // ?!
+	case Mistake:
+		return 2 // ?
+	case Blunder:
+		return 4 // ??
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package eval
+
+import "testing"
+
+func TestWhiteScore(t *testing.T) {
+	tests := []struct {
+		eval        *MoveEval
+		whiteToMove bool
+		expected    int
+	}{
+		{&MoveEval{Score: 35}, true, 35},
+		{&MoveEval{Score: -35}, false, -35},
+		{&MoveEval{Score: 2500}, true, MaxScore},
+		{&MoveEval{Score: -2500}, false, -MaxScore},
+		{&MoveEval{Mate: true, MateIn: 3}, true, MaxScore},
+		{&MoveEval{Mate: true, MateIn: 3}, false, -MaxScore},
+		{&MoveEval{Mate: true, MateIn: -2}, true, -MaxScore},
+		{&MoveEval{Mate: true, MateIn: 0}, false, MaxScore},
+	}
+
+	for _, tt := range tests {
+		actual := WhiteScore(tt.eval, tt.whiteToMove)
+		if actual != tt.expected {
+			t.Errorf("Expected: %d, got: %d", tt.expected, actual)
+		}
+	}
+}
+
+func TestClassifyMove(t *testing.T) {
+	tests := []struct {
+		before     *MoveEval
+		after      *MoveEval
+		whiteMoved bool
+		expected   Classification
+	}{
+		{&MoveEval{Score: 30}, &MoveEval{Score: 20}, true, Good},
+		{&MoveEval{Score: 30}, &MoveEval{Score: -30}, true, Inaccuracy},
+		{&MoveEval{Score: 30}, &MoveEval{Score: -100}, true, Mistake},
+		{&MoveEval{Score: 30}, &MoveEval{Score: -400}, true, Blunder},
+		{&MoveEval{Score: 30}, &MoveEval{Score: 400}, false, Blunder},
+		{&MoveEval{Score: 30}, &MoveEval{Score: -400}, false, Good},
+		// Already lost positions can't get much worse
+		{&MoveEval{Score: -1500}, &MoveEval{Score: -2500}, true, Good},
+		// Missing a forced mate
+		{&MoveEval{Mate: true, MateIn: 2}, &MoveEval{Score: 500}, true, Blunder},
+		// Walking into a forced mate
+		{&MoveEval{Score: 0}, &MoveEval{Mate: true, MateIn: 1}, true, Blunder},
+		{&MoveEval{Mate: true, MateIn: 3}, &MoveEval{Mate: true, MateIn: -2}, false, Good},
+		// Dropping two pawns in a won endgame that is still won
+		{&MoveEval{Score: 700, WDL: [3]int{990, 10, 0}}, &MoveEval{Score: 500, WDL: [3]int{960, 40, 0}}, true, Good},
+		// Losing half a pawn when it turns a draw into a loss
+		{&MoveEval{Score: -120, WDL: [3]int{0, 800, 200}}, &MoveEval{Score: -170, WDL: [3]int{0, 400, 600}}, true, Blunder},
+		{&MoveEval{Score: 20, WDL: [3]int{100, 850, 50}}, &MoveEval{Score: 90, WDL: [3]int{200, 780, 20}}, false, Inaccuracy},
+		{&MoveEval{Score: 20, WDL: [3]int{100, 850, 50}}, &MoveEval{Score: 150, WDL: [3]int{300, 680, 20}}, false, Mistake},
+		// Falls back to centipawns if only one position has WDL
+		{&MoveEval{Score: 30}, &MoveEval{Score: -400, WDL: [3]int{0, 100, 900}}, true, Blunder},
+	}
+
+	for _, tt := range tests {
+		actual := ClassifyMove(tt.before, tt.after, tt.whiteMoved)
+		if actual != tt.expected {
+			t.Errorf("Expected: %d, got: %d", tt.expected, actual)
+		}
+	}
+}
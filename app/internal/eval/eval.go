@@ -1,59 +1,68 @@
 package eval
 
 import (
-	"bufio"
+	"context"
 	"errors"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Engine struct {
-	cmd        *exec.Cmd
-	writer     *bufio.Writer
-	scanner    *bufio.Scanner
+	proc       *process
 	Path       string
-	Movetime   int    // ms spent on each move
-	Depth      int    // max depth to search
-	Threads    int    // number of threads to use
-	Hash       int    // hash table size (MB)
-	MultiPV    int    // number of lines to consider
-	SyzygyPath string // path to syzygy tablebases
+	Movetime   int      // ms spent on each move
+	Depth      int      // max depth to search
+	Threads    int      // number of threads to use
+	Hash       int      // hash table size (MB)
+	MultiPV    int      // number of lines to consider
+	SyzygyPath string   // path to syzygy tablebases
+	Variants   []string // variants the engine supports through UCI_Variant, empty if it only plays chess
+	Name       string   // sent by the engine in response to uci
+	Author     string
+	Options    []*Option     // advertised by the engine in response to uci
+	Timeout    time.Duration // how long the engine may go without responding before it's restarted
+	requests   chan *request // work for the goroutine owning the engine
+	closed     chan struct{}
+	closeOnce  sync.Once
+	mu         sync.Mutex // guards infinite and the status
+	infinite   *infiniteSearch
+	status     Status
+	statusErr  error
+	onStatus   func()
 }
 
 type MoveEval struct {
-	Depth    int
-	Score    int // centipawns
-	BestLine []string
-	Mate     bool
-	MateIn   int
-	PVnum    int
+	Depth      int
+	Score      int // centipawns
+	BestLine   []string
+	Mate       bool
+	MateIn     int
+	PVnum      int
+	SelDepth   int    // selective search depth
+	LowerBound bool   // the score is a lower bound from a search that failed high
+	UpperBound bool   // the score is an upper bound from a search that failed low
+	WDL        [3]int // chances of a win, draw and loss for white per mille, zero if not sent
+	Nodes      int
+	NPS        int // nodes per second
+	TBHits     int // positions found in the tablebases
+	HashFull   int // per mille of the hash table used
+	Time       int // ms searched
 }
 
 // NewEngine starts the provided engine and return a struct containing
-// the command handle and input/output pipes
+// the engine's process
 func NewEngine(filepath, Syzygy string, movetime, depth, threads, hash, multiPV int) (*Engine, error) {
 	if filepath == "" {
 		return nil, errors.New("no engine path provided")
 	}
-	cmd := exec.Command(filepath)
-	stdin, err := cmd.StdinPipe()
+	proc, err := startProcess(filepath)
 	if err != nil {
 		return nil, err
 	}
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
-	}
-	if err := cmd.Start(); err != nil {
-		return nil, err
-	}
-	writer := bufio.NewWriter(stdin)
-	scanner := bufio.NewScanner(stdout)
-	return &Engine{
-		cmd:        cmd,
-		writer:     writer,
-		scanner:    scanner,
+	eng := &Engine{
+		proc:       proc,
 		Path:       filepath,
 		Movetime:   movetime,
 		Depth:      depth,
@@ -61,40 +70,57 @@ func NewEngine(filepath, Syzygy string, movetime, depth, threads, hash, multiPV
 		Hash:       hash,
 		MultiPV:    multiPV,
 		SyzygyPath: Syzygy,
-	}, nil
+		Timeout:    DefaultTimeout,
+		requests:   make(chan *request),
+		closed:     make(chan struct{}),
+	}
+	go eng.serve()
+	return eng, nil
 }
 
 // SendCommand sends a command to the engine
+//
+// Commands sent directly aren't queued with the engine's other work, so
+// this should only be used before the engine is shared
 func (e *Engine) SendCommand(command string) error {
-	_, err := e.writer.WriteString(command + "\n")
-	if err != nil {
-		return err
+	e.proc.writer.WriteString(command + "\n")
+	if e.proc.writer.Flush() != nil {
+		// the engine closed its input
+		return e.failure(ErrEngineExited)
 	}
-	return e.writer.Flush()
+	return nil
 }
 
-// ReadResponse reads the response from the engine
-func (e *Engine) ReadResponse() []string {
+// ReadResponse reads the response from the engine up to uciok, readyok or bestmove
+//
+// Returns an *EngineError with the lines read so far if the engine exits or
+// goes longer than its timeout without sending a line
+func (e *Engine) ReadResponse() ([]string, error) {
 	response := []string{}
-	endStrings := []string{"uciok", "readyok", "bestmove", "Stockfish 17 by the Stockfish developers (see AUTHORS file)"}
-	for e.scanner.Scan() {
-		line := e.scanner.Text()
+	for {
+		line, err := e.readLine(time.After(e.Timeout))
+		if err != nil {
+			return response, err
+		}
 		response = append(response, line)
-		for _, str := range endStrings {
-			if strings.Contains(line, str) {
-				return response
-			}
+		if line == "uciok" || line == "readyok" || strings.HasPrefix(line, "bestmove") {
+			return response, nil
 		}
 	}
-	return response
 }
 
-// Close closes the engine
+// Close closes the engine once the work queued before it has finished
+//
+// An engine that doesn't quit before its timeout is killed
 func (e *Engine) Close() error {
-	if err := e.SendCommand("quit"); err != nil {
-		return err
+	if e.Searching() {
+		e.Stop()
 	}
-	return e.cmd.Wait()
+	err := e.do(context.Background(), func() error {
+		return e.proc.quit(e.Timeout)
+	})
+	e.closeOnce.Do(func() { close(e.closed) })
+	return err
 }
 
 // Parses a score string and returns a MoveEval struct
@@ -119,6 +145,25 @@ func ParseScoreStr(scoreStr string) *MoveEval {
 	}
 }
 
+// Parses a WDL string and returns the chances of a win, draw and loss per mille
+//
+// Expected input: "95/880/25", or "-" if the engine didn't send WDL
+func ParseWDLStr(wdlStr string) [3]int {
+	wdl := [3]int{}
+	parts := strings.Split(wdlStr, "/")
+	if len(parts) != 3 {
+		return wdl
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return [3]int{}
+		}
+		wdl[i] = n
+	}
+	return wdl
+}
+
 func GetEvalNum(evals []*MoveEval, pvNum int) *MoveEval {
 	if len(evals) == 0 {
 		return nil
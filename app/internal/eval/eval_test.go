@@ -1,8 +1,12 @@
 package eval
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -43,6 +47,24 @@ func TestInitializeEngine(t *testing.T) {
 	}
 }
 
+func TestInitializeEngineOptions(t *testing.T) {
+	eng, err := InitializeEngine(FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
+	if err != nil {
+		t.Fatalf("InitializeEngine() failed: %v", err)
+	}
+	if eng.Name != "Stockfish 17" {
+		t.Errorf("InitializeEngine() failed: expected name Stockfish 17, got %v", eng.Name)
+	}
+	threads := eng.Option("threads")
+	if threads == nil || threads.Type != OptionSpin || threads.Value != fmt.Sprint(THREADS) {
+		t.Errorf("InitializeEngine() failed: expected the Threads option set to %d, got %v", THREADS, threads)
+	}
+	err = eng.Close()
+	if err != nil {
+		t.Errorf("Engine.Close() failed: %v", err)
+	}
+}
+
 // Tests both SendCommand and ReadResponse checking received response to see if
 // it matches the expected response from sending the command
 func TestSendCommandReadResponse(t *testing.T) {
@@ -50,19 +72,19 @@ func TestSendCommandReadResponse(t *testing.T) {
 	if err != nil {
 		t.Errorf("NewEngine(stockfish) failed: %v", err)
 	}
-	// initial message from stockfish
-	response := eng.ReadResponse()
-	expected := "Stockfish 17 by the Stockfish developers (see AUTHORS file)"
-	if response[len(response)-1] != expected {
-		t.Errorf("ReadResponse() failed: expected %v, got %v", expected, response)
-	}
-
-	// send uci command
+	// send uci command, the response follows the initial message from stockfish
 	err = eng.SendCommand("uci")
 	if err != nil {
 		t.Errorf("SendCommand(uci) failed: %v", err)
 	}
-	response = eng.ReadResponse()
+	response, err := eng.ReadResponse()
+	if err != nil {
+		t.Fatalf("ReadResponse() failed: %v", err)
+	}
+	expected := "Stockfish 17 by the Stockfish developers (see AUTHORS file)"
+	if response[0] != expected {
+		t.Errorf("ReadResponse() failed: expected %v, got %v", expected, response)
+	}
 	expected = "uciok"
 	if response[len(response)-1] != expected {
 		t.Errorf("ReadResponse() failed: expected %v, got %v", expected, response)
@@ -73,7 +95,10 @@ func TestSendCommandReadResponse(t *testing.T) {
 	if err != nil {
 		t.Errorf("SendCommand(isready) failed: %v", err)
 	}
-	response = eng.ReadResponse()
+	response, err = eng.ReadResponse()
+	if err != nil {
+		t.Fatalf("ReadResponse() failed: %v", err)
+	}
 	expected = "readyok"
 	if response[len(response)-1] != expected {
 		t.Errorf("ReadResponse() failed: expected %v, got %v", expected, response)
@@ -83,7 +108,10 @@ func TestSendCommandReadResponse(t *testing.T) {
 	eng.SendCommand("ucinewgame")
 	eng.SendCommand("position startpos moves e2e4 e7e5")
 	eng.SendCommand("go movetime 1000ms")
-	response = eng.ReadResponse()
+	response, err = eng.ReadResponse()
+	if err != nil {
+		t.Fatalf("ReadResponse() failed: %v", err)
+	}
 	expected = "bestmove g1f3 ponder b8c6"
 	if response[len(response)-1] != expected {
 		t.Errorf("ReadResponse() failed: expected %v, got %v", expected, response[len(response)-1])
@@ -95,7 +123,10 @@ func TestEvalPosition(t *testing.T) {
 	if err != nil {
 		t.Errorf("InitializeStockfish() failed: %v", err)
 	}
-	eval := eng.EvalPosition("e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4")
+	eval, err := eng.EvalPosition(context.Background(), "e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4")
+	if err != nil {
+		t.Errorf("EvalPosition() failed: %v", err)
+	}
 	if eval == nil {
 		t.Errorf("EvalPosition() failed: returned nil")
 	} else {
@@ -111,13 +142,37 @@ func TestEvalPosition(t *testing.T) {
 	}
 }
 
+func TestEvalFEN(t *testing.T) {
+	eng, err := InitializeEngine(FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
+	if err != nil {
+		t.Errorf("InitializeEngine() failed: %v", err)
+	}
+	eval, err := eng.EvalFEN(context.Background(), "r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5Q2/PPPP1PPP/RNB1K1NR w KQkq - 4 4")
+	if err != nil {
+		t.Errorf("EvalFEN() failed: %v", err)
+	}
+	if eval == nil {
+		t.Errorf("EvalFEN() failed: returned nil")
+	} else {
+		if !eval[0].Mate || eval[0].MateIn != 1 {
+			t.Errorf("EvalFEN() failed: expected mate in 1, got %v", eval[0])
+		}
+		if len(eval[0].BestLine) == 0 || eval[0].BestLine[0] != "f3f7" {
+			t.Errorf("EvalFEN() failed: expected best move f3f7, got %v", eval[0].BestLine)
+		}
+	}
+}
+
 func TestEvalGame(t *testing.T) {
 	eng, err := InitializeStockfish(FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
 	if err != nil {
 		t.Errorf("InitializeStockfish() failed: %v", err)
 	}
 	positionString := "e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4"
-	eval := eng.EvalGame(positionString)
+	eval, err := eng.EvalGame(context.Background(), positionString)
+	if err != nil {
+		t.Errorf("EvalGame() failed: %v", err)
+	}
 	expected := strings.Split(positionString, " ")
 	if len(eval) != len(expected)+1 {
 		t.Errorf("EvalGame() failed: expected %v moves, got %v", len(expected)+1, len(eval))
@@ -145,7 +200,10 @@ func TestMultiPV(t *testing.T) {
 		t.Errorf("InitializeStockfish() failed: %v", err)
 	}
 	positionString := "e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4"
-	eval := eng.EvalGame(positionString)
+	eval, err := eng.EvalGame(context.Background(), positionString)
+	if err != nil {
+		t.Errorf("EvalGame() failed: %v", err)
+	}
 	for i, moveEval := range eval {
 		if len(moveEval) != 3 {
 			t.Errorf("EvalGame() failed: expected 3 moveEvals, got %v at index %v", len(moveEval), i)
@@ -161,3 +219,197 @@ func TestMultiPV(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWDLStr(t *testing.T) {
+	tests := []struct {
+		wdlStr   string
+		expected [3]int
+	}{
+		{"95/880/25", [3]int{95, 880, 25}},
+		{"0/0/1000", [3]int{0, 0, 1000}},
+		{"-", [3]int{}},
+		{"95/880", [3]int{}},
+		{"95/x/25", [3]int{}},
+	}
+
+	for _, tt := range tests {
+		actual := ParseWDLStr(tt.wdlStr)
+		if actual != tt.expected {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.wdlStr, actual)
+		}
+	}
+}
+
+func TestParseVariantOption(t *testing.T) {
+	tests := []struct {
+		line     string
+		variants []string
+		ok       bool
+	}{
+		{"option name UCI_Variant type combo default chess var chess var atomic var 3check var kingofthehill", []string{"chess", "atomic", "3check", "kingofthehill"}, true},
+		{"option name UCI_Chess960 type check default false", nil, false},
+		{"id name Stockfish 17", nil, false},
+	}
+
+	for _, tt := range tests {
+		variants, ok := parseVariantOption(tt.line)
+		if ok != tt.ok {
+			t.Errorf("Expected ok %v for %q, got %v", tt.ok, tt.line, ok)
+		}
+		if strings.Join(variants, " ") != strings.Join(tt.variants, " ") {
+			t.Errorf("Expected variants %v for %q, got %v", tt.variants, tt.line, variants)
+		}
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		line     string
+		turnMult int
+		expected *Info
+		err      error
+	}{
+		{
+			"info depth 18 seldepth 24 multipv 2 score cp 35 wdl 95 880 25 nodes 412345 nps 1234567 hashfull 120 tbhits 0 time 334 pv e2e4 e7e5 g1f3",
+			1,
+			&Info{Depth: 18, SelDepth: 24, PVnum: 2, Score: 35, WDL: [3]int{95, 880, 25}, Nodes: 412345, NPS: 1234567, HashFull: 120, Time: 334, PV: []string{"e2e4", "e7e5", "g1f3"}},
+			nil,
+		},
+		{
+			// Scores and WDL are from white's perspective when black is to move
+			"info depth 10 seldepth 12 multipv 1 score cp 50 wdl 300 600 100 nodes 1000 nps 100000 pv e7e5",
+			-1,
+			&Info{Depth: 10, SelDepth: 12, PVnum: 1, Score: -50, WDL: [3]int{100, 600, 300}, Nodes: 1000, NPS: 100000, PV: []string{"e7e5"}},
+			nil,
+		},
+		{
+			"info depth 5 score mate -2 pv f7f6 d1h5",
+			1,
+			&Info{Depth: 5, PVnum: 1, Mate: true, MateIn: -2, PV: []string{"f7f6", "d1h5"}},
+			nil,
+		},
+		{
+			"info depth 12 seldepth 16 multipv 1 score cp 20 upperbound nodes 5000 nps 500000 tbhits 3 time 10 pv d2d4",
+			1,
+			&Info{Depth: 12, SelDepth: 16, PVnum: 1, Score: 20, UpperBound: true, Nodes: 5000, NPS: 500000, TBHits: 3, Time: 10, PV: []string{"d2d4"}},
+			nil,
+		},
+		{
+			// a lower bound for black is an upper bound for white
+			"info depth 12 score cp 20 lowerbound pv d7d5",
+			-1,
+			&Info{Depth: 12, PVnum: 1, Score: -20, UpperBound: true, PV: []string{"d7d5"}},
+			nil,
+		},
+		{"info depth 20 currmove e2e4 currmovenumber 1", 1, nil, ErrNoScore},
+		{"info string NNUE evaluation using nn-1111cefa1111.nnue", 1, nil, ErrNoScore},
+		{"bestmove e2e4 ponder e7e5", 1, nil, ErrNoScore},
+	}
+
+	for _, tt := range tests {
+		info, err := parseInfo(tt.line, tt.turnMult)
+		if err != tt.err {
+			t.Errorf("Expected error %v for %q, got %v", tt.err, tt.line, err)
+		}
+		if !reflect.DeepEqual(info, tt.expected) {
+			t.Errorf("Expected %+v for %q, got %+v", tt.expected, tt.line, info)
+		}
+	}
+}
+
+func TestInfinite(t *testing.T) {
+	eng, err := InitializeEngine(FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
+	if err != nil {
+		t.Fatalf("InitializeEngine() failed: %v", err)
+	}
+	defer eng.Close()
+	_, err = eng.Stop()
+	if err != ErrNotSearching {
+		t.Errorf("Stop() failed: expected %v, got %v", ErrNotSearching, err)
+	}
+	infos := make(chan *Info, 1000)
+	err = eng.StartInfinite(context.Background(), "", "e2e4 e7e5", func(info *Info) {
+		select {
+		case infos <- info:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatalf("StartInfinite() failed: %v", err)
+	}
+	if !eng.Searching() {
+		t.Errorf("Searching() failed: expected true")
+	}
+	err = eng.StartInfinite(context.Background(), "", "", nil)
+	if err != ErrSearching {
+		t.Errorf("StartInfinite() failed: expected %v, got %v", ErrSearching, err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	evals, err := eng.Stop()
+	if err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if eng.Searching() {
+		t.Errorf("Searching() failed: expected false after Stop()")
+	}
+	if len(evals) == 0 || evals[0].Depth == 0 || len(evals[0].BestLine) == 0 {
+		t.Errorf("Stop() failed: expected an eval with a best line, got %v", evals)
+	}
+	if len(infos) == 0 {
+		t.Errorf("StartInfinite() failed: expected info lines while searching")
+	}
+}
+
+func TestPositionCommand(t *testing.T) {
+	tests := []struct {
+		fen            string
+		positionString string
+		command        string
+		turnMult       int
+	}{
+		{"", "", "position startpos moves ", 1},
+		{"", "e2e4", "position startpos moves e2e4", -1},
+		{"4k3/8/8/8/8/8/8/4K3 b - - 0 1", "", "position fen 4k3/8/8/8/8/8/8/4K3 b - - 0 1 moves ", -1},
+		{"4k3/8/8/8/8/8/8/4K3 b - - 0 1", "e8d8", "position fen 4k3/8/8/8/8/8/8/4K3 b - - 0 1 moves e8d8", 1},
+	}
+
+	for _, tt := range tests {
+		command, turnMult := positionCommand(tt.fen, tt.positionString)
+		if command != tt.command || turnMult != tt.turnMult {
+			t.Errorf("Expected %q and %d, got %q and %d", tt.command, tt.turnMult, command, turnMult)
+		}
+	}
+}
+
+func TestEvalGameCancel(t *testing.T) {
+	eng, err := InitializeEngine(FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
+	if err != nil {
+		t.Fatalf("InitializeEngine() failed: %v", err)
+	}
+	defer eng.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Duration(MOVETIME)*time.Millisecond)
+	defer cancel()
+	positionString := "e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4"
+	eval, err := eng.EvalGame(ctx, positionString)
+	if err != context.DeadlineExceeded {
+		t.Errorf("EvalGame() failed: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if len(eval) == 0 || eval[0] == nil || eval[len(eval)-1] != nil {
+		t.Errorf("EvalGame() failed: expected only the first moves to be evaluated, got %v", eval)
+	}
+
+	// The stopped search doesn't affect the next one
+	fenEval, err := eng.EvalFEN(context.Background(), "r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5Q2/PPPP1PPP/RNB1K1NR w KQkq - 4 4")
+	if err != nil {
+		t.Fatalf("EvalFEN() failed: %v", err)
+	}
+	if len(fenEval[0].BestLine) == 0 || fenEval[0].BestLine[0] != "f3f7" {
+		t.Errorf("EvalFEN() failed: expected best move f3f7, got %v", fenEval[0].BestLine)
+	}
+
+	// A cancelled context doesn't start a search
+	_, err = eng.EvalFEN(ctx, "")
+	if err != context.DeadlineExceeded {
+		t.Errorf("EvalFEN() failed: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
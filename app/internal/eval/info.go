@@ -0,0 +1,156 @@
+package eval
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrNoScore = errors.New("info line has no score")
+
+// Search information sent by the engine in an info line while it searches
+//
+// Scores and their bounds are from white's perspective, mates from the perspective of the side to move
+type Info struct {
+	Depth      int
+	SelDepth   int // selective search depth
+	PVnum      int
+	Score      int // centipawns
+	Mate       bool
+	MateIn     int
+	LowerBound bool   // the search failed high, the score is at least Score
+	UpperBound bool   // the search failed low, the score is at most Score
+	WDL        [3]int // chances of a win, draw and loss for white per mille, zero if not sent
+	Nodes      int
+	NPS        int // nodes per second
+	TBHits     int // positions found in the tablebases
+	HashFull   int // per mille of the hash table used
+	Time       int // ms searched
+	PV         []string
+}
+
+// Called with each info line with a score sent while the engine searches
+type InfoHandler func(info *Info)
+
+// Parses an info line sent by the engine
+//
+// turnMult is -1 if black is to move so scores are from white's perspective
+//
+// Expected input: "info depth 20 seldepth 28 multipv 1 score cp 35 wdl 95 880 25 nodes 1000 nps 5000 hashfull 10 tbhits 0 time 200 pv e2e4 e7e5"
+func parseInfo(line string, turnMult int) (*Info, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return nil, ErrNoScore
+	}
+	info := &Info{PVnum: 1}
+	scored := false
+	var err error
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			info.Depth, err = intField(fields, i+1)
+			i++
+		case "seldepth":
+			info.SelDepth, err = intField(fields, i+1)
+			i++
+		case "multipv":
+			info.PVnum, err = intField(fields, i+1)
+			i++
+		case "nodes":
+			info.Nodes, err = intField(fields, i+1)
+			i++
+		case "nps":
+			info.NPS, err = intField(fields, i+1)
+			i++
+		case "tbhits":
+			info.TBHits, err = intField(fields, i+1)
+			i++
+		case "hashfull":
+			info.HashFull, err = intField(fields, i+1)
+			i++
+		case "time":
+			info.Time, err = intField(fields, i+1)
+			i++
+		case "lowerbound":
+			info.LowerBound = true
+		case "upperbound":
+			info.UpperBound = true
+		case "score":
+			if i+1 >= len(fields) {
+				return nil, ErrNoScore
+			}
+			info.Mate = fields[i+1] == "mate"
+			var score int
+			score, err = intField(fields, i+2)
+			if info.Mate {
+				info.MateIn = score
+			} else {
+				info.Score = score * turnMult
+			}
+			scored = true
+			i += 2
+		case "wdl":
+			for j := range info.WDL {
+				info.WDL[j], err = intField(fields, i+1+j)
+				if err != nil {
+					break
+				}
+			}
+			if turnMult == -1 {
+				info.WDL[0], info.WDL[2] = info.WDL[2], info.WDL[0]
+			}
+			i += 3
+		case "pv":
+			info.PV = fields[i+1:]
+			i = len(fields)
+		case "string":
+			// the rest of the line is free text
+			i = len(fields)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !scored {
+		return nil, ErrNoScore
+	}
+	if turnMult == -1 && !info.Mate {
+		// a lower bound for black is an upper bound for white
+		info.LowerBound, info.UpperBound = info.UpperBound, info.LowerBound
+	}
+	return info, nil
+}
+
+// Parses the integer at the index of the fields
+func intField(fields []string, i int) (int, error) {
+	if i >= len(fields) {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(fields[i])
+}
+
+// Returns true if the score is exact rather than a bound from a failed search
+func (info *Info) Exact() bool {
+	return !info.LowerBound && !info.UpperBound
+}
+
+// Converts the info to the eval of its line
+func (info *Info) MoveEval() *MoveEval {
+	return &MoveEval{
+		Depth:      info.Depth,
+		Score:      info.Score,
+		BestLine:   info.PV,
+		Mate:       info.Mate,
+		MateIn:     info.MateIn,
+		PVnum:      info.PVnum,
+		SelDepth:   info.SelDepth,
+		LowerBound: info.LowerBound,
+		UpperBound: info.UpperBound,
+		WDL:        info.WDL,
+		Nodes:      info.Nodes,
+		NPS:        info.NPS,
+		TBHits:     info.TBHits,
+		HashFull:   info.HashFull,
+		Time:       info.Time,
+	}
+}
@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests parseResponse with the output of several engines searching until bestmove
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		file     string   // output recorded in testdata
+		response []string // used if there is no file
+		multiPV  int
+		turnMult int
+		expected []*MoveEval
+	}{
+		{
+			file:     "stockfish17_multipv.txt",
+			multiPV:  3,
+			turnMult: -1,
+			expected: []*MoveEval{
+				{Depth: 17, SelDepth: 24, PVnum: 1, Score: -28, WDL: [3]int{23, 933, 44}, Nodes: 498731, NPS: 1240624, HashFull: 141, Time: 402, BestLine: []string{"e7e5", "g1f3", "b8c6", "f1b5"}},
+				{Depth: 17, SelDepth: 22, PVnum: 2, Score: -36, WDL: [3]int{17, 920, 63}, Nodes: 498731, NPS: 1240624, HashFull: 141, Time: 402, BestLine: []string{"c7c5", "g1f3", "d7d6"}},
+				{Depth: 17, SelDepth: 21, PVnum: 3, Score: -45, WDL: [3]int{12, 898, 90}, Nodes: 498731, NPS: 1240624, HashFull: 141, Time: 402, BestLine: []string{"e7e6", "d2d4", "d7d5"}},
+			},
+		},
+		{
+			file:     "stockfish17_mate.txt",
+			multiPV:  1,
+			turnMult: -1,
+			expected: []*MoveEval{
+				{Depth: 245, SelDepth: 2, PVnum: 1, Mate: true, MateIn: 1, WDL: [3]int{0, 0, 1000}, Nodes: 2941, NPS: 1470500, Time: 2, BestLine: []string{"d8h4"}},
+			},
+		},
+		{
+			// only one legal move, so fewer lines than MultiPV
+			file:     "stockfish17_forced.txt",
+			multiPV:  3,
+			turnMult: 1,
+			expected: []*MoveEval{
+				{Depth: 20, SelDepth: 8, PVnum: 1, Score: -1245, WDL: [3]int{0, 0, 1000}, Nodes: 18220, NPS: 1822000, HashFull: 2, TBHits: 1931, Time: 10, BestLine: []string{"e1f1", "h2h1"}},
+			},
+		},
+		{
+			file:     "lc0.txt",
+			multiPV:  1,
+			turnMult: 1,
+			expected: []*MoveEval{
+				{Depth: 5, SelDepth: 9, PVnum: 1, Score: 13, WDL: [3]int{174, 741, 85}, Nodes: 306, NPS: 712, HashFull: 1, Time: 1462, BestLine: []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"}},
+			},
+		},
+		{
+			// the search fails low twice before bestmove, keeping the last exact score
+			file:     "berserk.txt",
+			multiPV:  1,
+			turnMult: 1,
+			expected: []*MoveEval{
+				{Depth: 14, SelDepth: 19, PVnum: 1, Score: 21, Nodes: 89432, NPS: 2884903, HashFull: 4, Time: 31, BestLine: []string{"e2e4", "e7e5", "g1f3", "b8c6"}},
+			},
+		},
+		{
+			file:     "komodo.txt",
+			multiPV:  2,
+			turnMult: 1,
+			expected: []*MoveEval{
+				{Depth: 10, SelDepth: 14, PVnum: 1, Score: 30, Nodes: 40113, NPS: 1823318, HashFull: 1, Time: 22, BestLine: []string{"d2d4", "d7d5", "c2c4", "e7e6"}},
+				{Depth: 10, SelDepth: 13, PVnum: 2, Score: 24, Nodes: 40113, NPS: 1823318, HashFull: 1, Time: 22, BestLine: []string{"e2e4", "c7c5", "g1f3"}},
+			},
+		},
+		{
+			// a bound is only used if there is no exact score, a lower bound for black is an upper bound for white
+			response: []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 40 lowerbound nodes 20 nps 20000 pv e7e5",
+				"bestmove e7e5",
+			},
+			multiPV:  1,
+			turnMult: -1,
+			expected: []*MoveEval{
+				{Depth: 1, SelDepth: 1, PVnum: 1, Score: -40, UpperBound: true, Nodes: 20, NPS: 20000, BestLine: []string{"e7e5"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		response := tt.response
+		if tt.file != "" {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			response = strings.Split(strings.TrimSpace(string(data)), "\n")
+		}
+		eng := &Engine{MultiPV: tt.multiPV}
+		evals, err := eng.parseResponse(response, tt.turnMult)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", tt.file, err)
+			continue
+		}
+		if len(evals) != len(tt.expected) {
+			t.Errorf("Expected %d lines for %s, got %d", len(tt.expected), tt.file, len(evals))
+			continue
+		}
+		for i := range evals {
+			if !reflect.DeepEqual(evals[i], tt.expected[i]) {
+				t.Errorf("Expected %+v for line %d of %s, got %+v", tt.expected[i], i+1, tt.file, evals[i])
+			}
+		}
+	}
+}
+
+func TestParseResponseNoScore(t *testing.T) {
+	eng := &Engine{MultiPV: 1}
+	_, err := eng.parseResponse([]string{"info depth 1 currmove e2e4 currmovenumber 1", "bestmove e2e4"}, 1)
+	if err != ErrNoEval {
+		t.Errorf("Expected %v, got %v", ErrNoEval, err)
+	}
+}
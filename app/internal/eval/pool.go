@@ -0,0 +1,152 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Engine processes sharing the work of evaluating a game
+//
+// The threads and hash table are divided between the engines
+type Pool struct {
+	Engines []*Engine
+	Threads int // number of threads shared between the engines
+	Hash    int // hash table size (MB) shared between the engines
+}
+
+// Starts n engine processes, each with a share of the threads and hash table
+func InitializeEnginePool(n int, filepath, SyzygyPath string, moveTime, depth, threads, hash, MultiPV int) (*Pool, error) {
+	if n < 1 {
+		n = 1
+	}
+	pool := &Pool{
+		Threads: threads,
+		Hash:    hash,
+	}
+	for range n {
+		eng, err := InitializeEngine(filepath, SyzygyPath, moveTime, depth, share(threads, n), share(hash, n), MultiPV)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		pool.Engines = append(pool.Engines, eng)
+	}
+	return pool, nil
+}
+
+// Divides a resource between the engines, giving each at least 1
+func share(total, n int) int {
+	return max(1, total/n)
+}
+
+// Evaluates the game using the engines
+//
+// Returns an eval for each move in the game
+func (p *Pool) EvalGame(ctx context.Context, positionString string) ([][]*MoveEval, error) {
+	return p.EvalGameStream(ctx, "", positionString, nil)
+}
+
+// Evaluates a game starting from the position described by a FEN string, each
+// engine taking the next ply to be evaluated once it has finished the last
+//
+// onInfo is called from each engine's goroutine with the ply being searched and each
+// line of search information. If the context is cancelled the searches are stopped,
+// returning the evals of the moves searched so far and the context's error.
+// An empty fen starts from the initial position
+func (p *Pool) EvalGameStream(ctx context.Context, fen, positionString string, onInfo func(ply int, info *Info)) ([][]*MoveEval, error) {
+	moves := strings.Fields(positionString)
+	gameEval := make([][]*MoveEval, len(moves)+1)
+	plies := plyQueue(len(gameEval))
+	errs := make([]error, len(p.Engines))
+	var wg sync.WaitGroup
+	for i, eng := range p.Engines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = eng.do(ctx, func() error {
+				return eng.evalPlies(ctx, fen, moves, plies, gameEval, onInfo)
+			})
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return gameEval, ctx.Err()
+	}
+	return gameEval, errors.Join(errs...)
+}
+
+// Returns true if the engines can play the variant
+func (p *Pool) SupportsVariant(variant string) bool {
+	return p.Engines[0].SupportsVariant(variant)
+}
+
+// Sets the variant the engines play with UCI_Variant
+func (p *Pool) SetVariant(variant string) error {
+	for _, eng := range p.Engines {
+		err := eng.SetVariant(variant)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets an option of the engines, dividing the threads and hash table between them
+//
+// The pool's totals are only changed once every engine has taken its share
+func (p *Pool) ChangeOption(option, value string) error {
+	total := 0
+	switch option {
+	case "Threads", "Hash":
+		var err error
+		total, err = strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		value = strconv.Itoa(share(total, len(p.Engines)))
+	}
+	for _, eng := range p.Engines {
+		err := eng.ChangeOption(option, value)
+		if err != nil {
+			return err
+		}
+	}
+	switch option {
+	case "Threads":
+		p.Threads = total
+	case "Hash":
+		p.Hash = total
+	}
+	return nil
+}
+
+// Returns the status of the engine furthest from running, and its error
+func (p *Pool) Status() (Status, error) {
+	status, err := StatusRunning, error(nil)
+	for _, eng := range p.Engines {
+		engStatus, engErr := eng.Status()
+		if engStatus > status {
+			status, err = engStatus, engErr
+		}
+	}
+	return status, err
+}
+
+// Sets a function called whenever the status of one of the engines changes
+func (p *Pool) OnStatus(onStatus func()) {
+	for _, eng := range p.Engines {
+		eng.OnStatus(onStatus)
+	}
+}
+
+// Closes the engines
+func (p *Pool) Close() error {
+	errs := make([]error, len(p.Engines))
+	for i, eng := range p.Engines {
+		errs[i] = eng.Close()
+	}
+	return errors.Join(errs...)
+}
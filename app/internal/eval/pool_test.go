@@ -0,0 +1,54 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestShare(t *testing.T) {
+	tests := []struct {
+		total    int
+		n        int
+		expected int
+	}{
+		{12, 1, 12},
+		{12, 4, 3},
+		{256, 3, 85},
+		{2, 4, 1},
+	}
+
+	for _, tt := range tests {
+		actual := share(tt.total, tt.n)
+		if actual != tt.expected {
+			t.Errorf("Expected %d, got %d", tt.expected, actual)
+		}
+	}
+}
+
+func TestPoolEvalGame(t *testing.T) {
+	pool, err := InitializeEnginePool(3, FILEPATH, SYZYGYPATH, MOVETIME, DEPTH, THREADS, HASH, MULTIPV)
+	if err != nil {
+		t.Fatalf("InitializeEnginePool() failed: %v", err)
+	}
+	defer pool.Close()
+	for _, eng := range pool.Engines {
+		if eng.Threads != THREADS/3 || eng.Hash != HASH/3 {
+			t.Errorf("Expected %d threads and %d MB hash, got %d and %d", THREADS/3, HASH/3, eng.Threads, eng.Hash)
+		}
+	}
+	positionString := "e2e4 e7e5 b1c3 b8c6 f2f4 e5f4 g1f3 f8b4 d2d4 b4c3 b2c3 d7d5 e4e5 f7f6 c1f4"
+	eval, err := pool.EvalGame(context.Background(), positionString)
+	if err != nil {
+		t.Fatalf("EvalGame() failed: %v", err)
+	}
+	expected := strings.Split(positionString, " ")
+	if len(eval) != len(expected)+1 {
+		t.Errorf("EvalGame() failed: expected %v moves, got %v", len(expected)+1, len(eval))
+	}
+	for i, moveEval := range eval {
+		if len(moveEval) == 0 || moveEval[0].Depth == 0 {
+			t.Errorf("EvalGame() failed: expected an eval at index %v, got %v", i, moveEval)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEngineExited  = errors.New("engine process exited")
+	ErrEngineTimeout = errors.New("engine stopped responding")
+)
+
+// How long an engine may go without responding before it's considered hung
+const DefaultTimeout = 15 * time.Second
+
+// The engine's process crashing or hanging
+//
+// Err is ErrEngineExited or ErrEngineTimeout, so the failure can be checked with errors.Is
+type EngineError struct {
+	Err    error
+	Exit   error  // the exit status of a process that exited, nil if it exited cleanly
+	Stderr string // the last output the engine wrote to stderr
+}
+
+func (err *EngineError) Error() string {
+	msg := err.Err.Error()
+	if err.Exit != nil {
+		msg += fmt.Sprintf(" (%v)", err.Exit)
+	}
+	if err.Stderr != "" {
+		msg += ": " + err.Stderr
+	}
+	return msg
+}
+
+func (err *EngineError) Unwrap() error {
+	return err.Err
+}
+
+// State of the engine's process
+type Status int
+
+const (
+	StatusRunning    Status = iota
+	StatusRestarting        // crashed or hung and is being restarted
+	StatusFailed            // couldn't be restarted
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusRestarting:
+		return "restarting"
+	case StatusFailed:
+		return "failed"
+	}
+	return "running"
+}
+
+// A running engine process
+type process struct {
+	cmd     *exec.Cmd
+	writer  *bufio.Writer
+	stderr  *stderrTail
+	lines   chan string   // lines written to stdout, closed once stdout is closed
+	discard chan struct{} // closed once the output is no longer read
+	exited  chan struct{} // closed once the process has exited
+	exit    error         // exit status, set before exited is closed
+	once    sync.Once
+}
+
+// Starts the engine at the path, reading its output in the background
+func startProcess(path string) (*process, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &stderrTail{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &process{
+		cmd:     cmd,
+		writer:  bufio.NewWriter(stdin),
+		stderr:  stderr,
+		lines:   make(chan string),
+		discard: make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case p.lines <- scanner.Text():
+			case <-p.discard:
+			}
+		}
+		close(p.lines)
+		// stdout must be read to the end before waiting
+		p.exit = cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// Stops reading the process's output so it can exit
+func (p *process) stopReading() {
+	p.once.Do(func() { close(p.discard) })
+}
+
+// Kills the process
+func (p *process) kill() {
+	p.stopReading()
+	p.cmd.Process.Kill()
+}
+
+// Asks the process to quit, killing it if it hasn't exited once the timeout has passed
+func (p *process) quit(timeout time.Duration) error {
+	p.writer.WriteString("quit\n")
+	p.writer.Flush()
+	p.stopReading()
+	select {
+	case <-p.exited:
+	case <-time.After(timeout):
+		p.kill()
+		<-p.exited
+	}
+	return p.exit
+}
+
+// Returns the error describing the process failing
+//
+// Waits for a process that closed its output to exit so its exit status can be included
+func (p *process) failure(err error, timeout time.Duration) *EngineError {
+	engineErr := &EngineError{Err: err}
+	if err == ErrEngineExited {
+		p.stopReading()
+		select {
+		case <-p.exited:
+			engineErr.Exit = p.exit
+		case <-time.After(timeout):
+		}
+	}
+	engineErr.Stderr = p.stderr.String()
+	return engineErr
+}
+
+// Keeps the end of the output an engine writes to stderr
+type stderrTail struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const stderrTailSize = 1024
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > stderrTailSize {
+		t.buf = t.buf[len(t.buf)-stderrTailSize:]
+	}
+	return len(p), nil
+}
+
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(bytes.TrimSpace(t.buf))
+}
+
+// Returns the error describing the engine's process failing
+func (e *Engine) failure(err error) *EngineError {
+	return e.proc.failure(err, e.Timeout)
+}
+
+// Reads the next line sent by the engine, failing if none is sent before the deadline
+func (e *Engine) readLine(deadline <-chan time.Time) (string, error) {
+	select {
+	case line, ok := <-e.proc.lines:
+		if !ok {
+			return "", e.failure(ErrEngineExited)
+		}
+		return line, nil
+	case <-deadline:
+		return "", e.failure(ErrEngineTimeout)
+	}
+}
+
+// Runs work with the engine, restarting the engine and running the work again
+// if the engine crashes or hangs
+//
+// Must be run by the goroutine owning the engine
+func (e *Engine) retry(run func() error) error {
+	err := run()
+	var engineErr *EngineError
+	if !errors.As(err, &engineErr) {
+		return err
+	}
+	restartErr := e.restart(engineErr)
+	if restartErr != nil {
+		return errors.Join(err, restartErr)
+	}
+	return run()
+}
+
+// Replaces the engine's process with a new one, setting the options that were set on the old one
+//
+// cause is the failure of the old process. Must be run by the goroutine owning the engine
+func (e *Engine) restart(cause error) error {
+	e.setStatus(StatusRestarting, cause)
+	e.proc.kill()
+	proc, err := startProcess(e.Path)
+	if err == nil {
+		e.proc = proc
+		err = e.restoreOptions()
+	}
+	if err != nil {
+		e.setStatus(StatusFailed, err)
+		return err
+	}
+	e.setStatus(StatusRunning, nil)
+	return nil
+}
+
+// Sets the options of a new process to the values set on the old one
+//
+// The options advertised by the old process are kept, as it was the same engine
+func (e *Engine) restoreOptions() error {
+	_, err := e.await("uci", "uciok")
+	if err != nil {
+		return err
+	}
+	for _, option := range e.Options {
+		if option.Type == OptionButton || option.Value == option.Default {
+			continue
+		}
+		err = e.SendCommand(fmt.Sprintf("setoption name %v value %v", option.Name, option.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return e.waitReady()
+}
+
+func (e *Engine) setStatus(status Status, err error) {
+	e.mu.Lock()
+	e.status = status
+	e.statusErr = err
+	onStatus := e.onStatus
+	e.mu.Unlock()
+	if onStatus != nil {
+		onStatus()
+	}
+}
+
+// Returns the status of the engine's process
+//
+// err is the failure the engine is restarting after, or the reason it couldn't be restarted
+func (e *Engine) Status() (status Status, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status, e.statusErr
+}
+
+// Sets a function called from the engine's goroutine whenever its status changes
+func (e *Engine) OnStatus(onStatus func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStatus = onStatus
+}
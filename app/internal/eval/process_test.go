@@ -0,0 +1,183 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeEngine = "testdata/fake_engine.sh"
+
+// Sets up the fake engine to fail, returning the path of the log of commands it receives
+func setupFakeEngine(t *testing.T, failure string) string {
+	log := filepath.Join(t.TempDir(), "commands.log")
+	t.Setenv("FAKE_ENGINE_LOG", log)
+	t.Setenv("FAKE_ENGINE_FAILURE", failure)
+	return log
+}
+
+func TestEngineRestart(t *testing.T) {
+	tests := []struct {
+		failure  string
+		moveTime int
+		expected error
+		stderr   string
+	}{
+		{"exit", 100, ErrEngineExited, "segmentation fault"},
+		{"hang", 100, ErrEngineTimeout, ""},
+		// a search limited only by depth
+		{"hang", 0, ErrEngineTimeout, ""},
+	}
+
+	for _, tt := range tests {
+		log := setupFakeEngine(t, tt.failure)
+		eng, err := InitializeEngine(fakeEngine, "", tt.moveTime, 1, 2, 16, 1)
+		if err != nil {
+			t.Fatalf("InitializeEngine() failed: %v", err)
+		}
+		eng.Timeout = 200 * time.Millisecond
+		statuses := []Status{}
+		var cause error
+		eng.OnStatus(func() {
+			status, err := eng.Status()
+			statuses = append(statuses, status)
+			if status == StatusRestarting {
+				cause = err
+			}
+		})
+
+		// the search is retried once the engine has restarted
+		evals, err := eng.EvalFEN(context.Background(), "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		if err != nil {
+			t.Fatalf("EvalFEN() failed after %v: %v", tt.failure, err)
+		}
+		if evals[0].Score != 35 {
+			t.Errorf("Expected the score of the restarted engine 35, got %d", evals[0].Score)
+		}
+		if len(statuses) != 2 || statuses[0] != StatusRestarting || statuses[1] != StatusRunning {
+			t.Errorf("Expected the engine to restart, got statuses %v", statuses)
+		}
+		var engineErr *EngineError
+		if !errors.Is(cause, tt.expected) || !errors.As(cause, &engineErr) || engineErr.Stderr != tt.stderr {
+			t.Errorf("Expected %v with stderr %q, got %v", tt.expected, tt.stderr, cause)
+		}
+
+		// the options are set again on the new process
+		commands, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatalf("Failed to read the commands: %v", err)
+		}
+		if n := strings.Count(string(commands), "setoption name Threads value 2\n"); n != 2 {
+			t.Errorf("Expected Threads to be set twice, got %d", n)
+		}
+		err = eng.Close()
+		if err != nil {
+			t.Errorf("Engine.Close() failed: %v", err)
+		}
+	}
+}
+
+func TestInitializeEngineExits(t *testing.T) {
+	setupFakeEngine(t, "handshake")
+	_, err := InitializeEngine(fakeEngine, "", 100, 1, 2, 16, 1)
+	if !errors.Is(err, ErrNotUCI) || !errors.Is(err, ErrEngineExited) {
+		t.Fatalf("Expected %v and %v, got %v", ErrNotUCI, ErrEngineExited, err)
+	}
+	if !strings.Contains(err.Error(), "failed to load the network") {
+		t.Errorf("Expected the error to include the engine's stderr, got %v", err)
+	}
+}
+
+func TestInfiniteSearchCancelled(t *testing.T) {
+	setupFakeEngine(t, "")
+	eng, err := InitializeEngine(fakeEngine, "", 100, 1, 2, 16, 1)
+	if err != nil {
+		t.Fatalf("InitializeEngine() failed: %v", err)
+	}
+	defer eng.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	err = eng.StartInfinite(ctx, "", "", nil)
+	if err != nil {
+		t.Fatalf("StartInfinite() failed: %v", err)
+	}
+	if !eng.Searching() {
+		t.Fatalf("Expected the engine to be searching")
+	}
+
+	// cancelling the context stops the search without Stop being called
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for eng.Searching() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if eng.Searching() {
+		t.Errorf("Expected the search to stop once its context was cancelled")
+	}
+	_, err = eng.Stop()
+	if err != ErrNotSearching {
+		t.Errorf("Expected %v, got %v", ErrNotSearching, err)
+	}
+	// the engine can be used again
+	evals, err := eng.EvalFEN(context.Background(), "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if err != nil || len(evals) != 1 {
+		t.Errorf("Expected an eval after the search was cancelled, got %v, %v", evals, err)
+	}
+}
+
+func TestEvalEmptyGame(t *testing.T) {
+	setupFakeEngine(t, "")
+	eng, err := InitializeEngine(fakeEngine, "", 100, 1, 2, 16, 1)
+	if err != nil {
+		t.Fatalf("InitializeEngine() failed: %v", err)
+	}
+	defer eng.Close()
+	pool, err := InitializeEnginePool(2, fakeEngine, "", 100, 1, 2, 16, 1)
+	if err != nil {
+		t.Fatalf("InitializeEnginePool() failed: %v", err)
+	}
+	defer pool.Close()
+
+	// a game without moves only has its starting position
+	fen := "4k3/8/8/8/8/8/8/4K3 w - - 0 1"
+	tests := []struct {
+		name     string
+		evalGame func(ctx context.Context, fen, positionString string, onInfo func(ply int, info *Info)) ([][]*MoveEval, error)
+	}{
+		{"Engine", eng.EvalGameStream},
+		{"Pool", pool.EvalGameStream},
+	}
+	for _, tt := range tests {
+		gameEval, err := tt.evalGame(context.Background(), fen, "", nil)
+		if err != nil {
+			t.Fatalf("%s.EvalGameStream() failed: %v", tt.name, err)
+		}
+		if len(gameEval) != 1 || len(gameEval[0]) != 1 {
+			t.Errorf("Expected %s to evaluate one position, got %v", tt.name, gameEval)
+		}
+	}
+}
+
+func TestPoolChangeOptionFails(t *testing.T) {
+	setupFakeEngine(t, "")
+	pool, err := InitializeEnginePool(2, fakeEngine, "", 100, 1, 2, 16, 1)
+	if err != nil {
+		t.Fatalf("InitializeEnginePool() failed: %v", err)
+	}
+	defer pool.Close()
+	pool.Engines[1].Close()
+
+	// the totals are kept when an engine doesn't take its share
+	for _, option := range []string{"Threads", "Hash"} {
+		err = pool.ChangeOption(option, "8")
+		if err != ErrEngineClosed {
+			t.Errorf("Expected %v, got %v", ErrEngineClosed, err)
+		}
+	}
+	if pool.Threads != 2 || pool.Hash != 16 {
+		t.Errorf("Expected 2 threads and 16 MB hash, got %d and %d", pool.Threads, pool.Hash)
+	}
+}
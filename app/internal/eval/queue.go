@@ -0,0 +1,81 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var ErrEngineClosed = errors.New("engine is closed")
+
+// States of a request in the engine's queue
+const (
+	requestQueued int32 = iota
+	requestRunning
+	requestAbandoned // the caller's context was cancelled before it ran
+)
+
+// Work done with the engine by the goroutine owning it
+type request struct {
+	ctx   context.Context
+	run   func() error
+	state atomic.Int32
+	done  chan error
+}
+
+// Runs the requests sent to the engine one at a time until it is closed
+//
+// Only this goroutine sends commands to the engine and reads its output, so
+// callers on other goroutines can't read each other's responses
+func (e *Engine) serve() {
+	for {
+		select {
+		case req := <-e.requests:
+			if !req.state.CompareAndSwap(requestQueued, requestRunning) {
+				continue
+			}
+			select {
+			case <-e.closed:
+				req.done <- ErrEngineClosed
+				return
+			default:
+			}
+			if err := req.ctx.Err(); err != nil {
+				req.done <- err
+				continue
+			}
+			req.done <- req.run()
+		case <-e.closed:
+			return
+		}
+	}
+}
+
+// Queues work for the engine's goroutine and waits for it to finish
+//
+// If the context is cancelled before the work starts it is skipped and the
+// context's error returned. Work that has started is left to finish, as it
+// stops itself once the context is cancelled
+func (e *Engine) do(ctx context.Context, run func() error) error {
+	req := &request{
+		ctx:  ctx,
+		run:  run,
+		done: make(chan error, 1),
+	}
+	select {
+	case e.requests <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.closed:
+		return ErrEngineClosed
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		if req.state.CompareAndSwap(requestQueued, requestAbandoned) {
+			return ctx.Err()
+		}
+		return <-req.done
+	}
+}
@@ -0,0 +1,109 @@
+package eval
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Returns an engine without a process that only runs queued work
+func newQueueEngine() *Engine {
+	eng := &Engine{
+		requests: make(chan *request),
+		closed:   make(chan struct{}),
+	}
+	go eng.serve()
+	return eng
+}
+
+func TestQueueSerializes(t *testing.T) {
+	eng := newQueueEngine()
+	defer close(eng.closed)
+
+	running := 0
+	overlapped := false
+	count := 0
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := eng.do(context.Background(), func() error {
+				running++
+				if running > 1 {
+					overlapped = true
+				}
+				time.Sleep(time.Millisecond)
+				count++
+				running--
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if overlapped {
+		t.Errorf("Expected requests to run one at a time")
+	}
+	if count != 20 {
+		t.Errorf("Expected 20 requests to run, got %d", count)
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	eng := newQueueEngine()
+	defer close(eng.closed)
+
+	// hold the engine until released
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go eng.do(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// a request cancelled while waiting is skipped
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := eng.do(ctx, func() error {
+		ran = true
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+
+	// the engine is still usable
+	err = eng.do(context.Background(), func() error { return nil })
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if ran {
+		t.Errorf("Expected the cancelled request not to run")
+	}
+
+	// a cancelled request doesn't start
+	err = eng.do(ctx, func() error {
+		ran = true
+		return nil
+	})
+	if err != context.DeadlineExceeded || ran {
+		t.Errorf("Expected the request to be skipped with %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestQueueClosed(t *testing.T) {
+	eng := newQueueEngine()
+	close(eng.closed)
+	err := eng.do(context.Background(), func() error { return nil })
+	if err != ErrEngineClosed {
+		t.Errorf("Expected %v, got %v", ErrEngineClosed, err)
+	}
+}
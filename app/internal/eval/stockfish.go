@@ -1,13 +1,34 @@
 package eval
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// Sends the commands to set up stockfish 17 specifically returning the engine
+var (
+	ErrVariantNotSupported = errors.New("variant not supported by the engine")
+	ErrSearching           = errors.New("engine is already searching")
+	ErrNotSearching        = errors.New("engine is not searching")
+	ErrNoEval              = errors.New("engine sent no scores")
+)
+
+// Starts Stockfish and sets it up for analysis, returning the engine
+//
+// Deprecated: any UCI engine can be started with InitializeEngine
 func InitializeStockfish(filepath, SyzygyPath string, moveTime, depth, threads, hash, MultiPV int) (*Engine, error) {
+	return InitializeEngine(filepath, SyzygyPath, moveTime, depth, threads, hash, MultiPV)
+}
+
+// Starts a UCI engine and sets it up for analysis, returning the engine
+//
+// Options the engine doesn't advertise are left unset, an engine without
+// MultiPV only searches one line
+func InitializeEngine(filepath, SyzygyPath string, moveTime, depth, threads, hash, MultiPV int) (*Engine, error) {
 	if filepath == "" {
 		return nil, fmt.Errorf("no engine path provided")
 	}
@@ -15,118 +36,422 @@ func InitializeStockfish(filepath, SyzygyPath string, moveTime, depth, threads,
 	if err != nil {
 		return nil, err
 	}
-	eng.SendCommand("uci")
-	for {
-		response := eng.ReadResponse()
-		if response[len(response)-1] == "uciok" {
-			break
-		}
+	err = eng.handshake()
+	if err != nil {
+		eng.proc.kill()
+		return nil, err
 	}
 	// Set options
-	eng.SendCommand(fmt.Sprintf("setoption name Threads value %d", threads))
-	eng.SendCommand(fmt.Sprintf("setoption name Hash value %d", hash))
-	eng.SendCommand(fmt.Sprintf("setoption name MultiPV value %d", MultiPV))
+	eng.setOption("Threads", threads)
+	eng.setOption("Hash", hash)
+	if eng.Option("MultiPV") == nil {
+		eng.MultiPV = 1
+	}
+	eng.setOption("MultiPV", eng.MultiPV)
 	if SyzygyPath != "" {
-		eng.SendCommand(fmt.Sprintf("setoption name SyzygyPath value %v", SyzygyPath))
+		eng.setOption("SyzygyPath", SyzygyPath)
 	}
-	eng.SendCommand("setoption name UCI_ShowWDL value true")
-	eng.SendCommand("isready")
-	for {
-		response := eng.ReadResponse()
-		if response[len(response)-1] == "readyok" {
-			break
-		}
+	eng.setOption("UCI_ShowWDL", true)
+	// Castling is sent as the king taking its own rook, which also works for standard games
+	eng.setOption("UCI_Chess960", true)
+	err = eng.waitReady()
+	if err != nil {
+		eng.proc.kill()
+		return nil, err
 	}
 	return eng, nil
 }
 
 // Evaluates the position string using the engine
 //
-// positionString is a space separated string of the moves in long algebraic notation
-func (e *Engine) EvalPosition(positionString string) []*MoveEval {
-	e.SendCommand("ucinewgame")
-	return e.queryPosition(positionString)
+// positionString is a space separated string of the moves in long algebraic notation.
+// If the context is cancelled the search is stopped and the context's error returned
+func (e *Engine) EvalPosition(ctx context.Context, positionString string) ([]*MoveEval, error) {
+	var evals []*MoveEval
+	err := e.do(ctx, func() error {
+		var err error
+		e.SendCommand("ucinewgame")
+		evals, err = e.queryPosition(ctx, "", positionString, nil)
+		return err
+	})
+	return evals, err
+}
+
+// Evaluates the position described by a FEN string using the engine
+func (e *Engine) EvalFEN(ctx context.Context, fen string) ([]*MoveEval, error) {
+	return e.EvalFENStream(ctx, fen, nil)
+}
+
+// Evaluates the position described by a FEN string, calling onInfo with
+// each line of search information as the engine sends it
+func (e *Engine) EvalFENStream(ctx context.Context, fen string, onInfo InfoHandler) ([]*MoveEval, error) {
+	var evals []*MoveEval
+	err := e.do(ctx, func() error {
+		var err error
+		e.SendCommand("ucinewgame")
+		evals, err = e.queryPosition(ctx, fen, "", onInfo)
+		return err
+	})
+	return evals, err
 }
 
 // Evaluates the game using the engine
 //
 // Returns an eval for each move in the game
-func (e *Engine) EvalGame(positionString string) [][]*MoveEval {
-	e.SendCommand("ucinewgame")
-	moves := strings.Split(positionString, " ")
+func (e *Engine) EvalGame(ctx context.Context, positionString string) ([][]*MoveEval, error) {
+	return e.EvalGameFromFEN(ctx, "", positionString)
+}
+
+// Evaluates a game starting from the position described by a FEN string
+//
+// An empty fen starts from the initial position
+func (e *Engine) EvalGameFromFEN(ctx context.Context, fen, positionString string) ([][]*MoveEval, error) {
+	return e.EvalGameStream(ctx, fen, positionString, nil)
+}
+
+// Evaluates a game starting from the position described by a FEN string, calling
+// onInfo with the ply being searched and each line of search information
+//
+// If the context is cancelled the search is stopped, returning the evals of the
+// moves searched so far and the context's error. An empty fen starts from the initial position
+//
+// The game is evaluated as a whole, so other work queued for the engine waits until it's done
+func (e *Engine) EvalGameStream(ctx context.Context, fen, positionString string, onInfo func(ply int, info *Info)) ([][]*MoveEval, error) {
+	moves := strings.Fields(positionString)
 	gameEval := make([][]*MoveEval, len(moves)+1)
-	for i := 0; i < len(moves)+1; i++ {
-		evals := e.queryPosition(strings.Join(moves[:i], " "))
+	err := e.do(ctx, func() error {
+		return e.evalPlies(ctx, fen, moves, plyQueue(len(gameEval)), gameEval, onInfo)
+	})
+	return gameEval, err
+}
+
+// Returns a closed channel of the plies of a game with the given number of positions, in order
+func plyQueue(positions int) chan int {
+	plies := make(chan int, positions)
+	for i := range positions {
+		plies <- i
+	}
+	close(plies)
+	return plies
+}
+
+// Evaluates the positions of a game taken from the plies channel until it is empty,
+// storing the evals of each ply in gameEval
+//
+// Must be run by the goroutine owning the engine
+func (e *Engine) evalPlies(ctx context.Context, fen string, moves []string, plies <-chan int, gameEval [][]*MoveEval, onInfo func(ply int, info *Info)) error {
+	e.SendCommand("ucinewgame")
+	for i := range plies {
+		var plyInfo InfoHandler
+		if onInfo != nil {
+			plyInfo = func(info *Info) { onInfo(i, info) }
+		}
+		evals, err := e.queryPosition(ctx, fen, strings.Join(moves[:i], " "), plyInfo)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var engineErr *EngineError
+		if errors.As(err, &engineErr) {
+			// the engine couldn't be restarted
+			return err
+		}
+		if err != nil {
+			// the other moves can still be evaluated
+			continue
+		}
 		gameEval[i] = evals
 	}
-	return gameEval
+	return nil
+}
+
+// Evaluates the position reached by playing the moves from the starting position
+func (e *Engine) queryPosition(ctx context.Context, fen, positionString string, onInfo InfoHandler) ([]*MoveEval, error) {
+	command, turnMult := positionCommand(fen, positionString)
+	return e.search(ctx, command, turnMult, onInfo)
 }
 
-func (e *Engine) queryPosition(positionString string) []*MoveEval {
-	e.SendCommand(fmt.Sprintf("position startpos moves %v", positionString))
-	e.SendCommand(fmt.Sprintf("go depth %v movetime %v", e.Depth, e.Movetime))
+// Returns the command setting up the position reached by playing the moves from
+// the position described by a FEN string, and -1 if black is to move or 1 if white is
+//
+// An empty fen starts from the initial position
+func positionCommand(fen, positionString string) (string, int) {
+	position := "startpos"
 	turnMult := 1
-	if len(strings.Split(positionString, " "))%2 == 1 && positionString != "" {
-		turnMult = -1
+	if fen != "" {
+		position = "fen " + fen
+		if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+			turnMult = -1
+		}
 	}
-	response := e.ReadResponse()
-	evals, err := e.parseResponse(response, turnMult)
-	if err != nil {
-		return nil
+	if len(strings.Fields(positionString))%2 == 1 {
+		turnMult = -turnMult
 	}
-	return evals
+	return fmt.Sprintf("position %v moves %v", position, positionString), turnMult
 }
 
-// Parses the response from the engine
-func (e *Engine) parseResponse(response []string, turnMult int) ([]*MoveEval, error) {
-	evals := make([]*MoveEval, e.MultiPV)
-	for i := range e.MultiPV {
-		eval := &MoveEval{}
-		if len(response) < 2+i {
-			return nil, fmt.Errorf("not enough responses")
-		}
-		dataLine := strings.Split(response[len(response)-2-i], " ")
-		for i, word := range dataLine {
-			if word == "depth" {
-				depth, err := strconv.Atoi(dataLine[i+1])
+// Sets up the position with the given command and searches it
+//
+// turnMult is -1 if black is to move so scores are from white's perspective. If the
+// engine crashes or hangs it's restarted and the position searched again
+func (e *Engine) search(ctx context.Context, positionCommand string, turnMult int, onInfo InfoHandler) ([]*MoveEval, error) {
+	var evals []*MoveEval
+	err := e.retry(func() error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := e.SendCommand(positionCommand)
+		if err != nil {
+			return err
+		}
+		err = e.SendCommand(fmt.Sprintf("go depth %v movetime %v", e.Depth, e.Movetime))
+		if err != nil {
+			return err
+		}
+		response, err := e.readSearch(ctx, e.Timeout, turnMult, onInfo)
+		if err != nil {
+			return err
+		}
+		evals, err = e.parseResponse(response, turnMult)
+		return err
+	})
+	return evals, err
+}
+
+// A search without a limit running in the background until it is stopped
+type infiniteSearch struct {
+	cancel context.CancelFunc // stops the search
+	evals  chan []*MoveEval   // receives the evals once the engine sends bestmove
+	err    error              // set before the evals are sent if the search failed
+}
+
+// Starts searching the position reached by playing the moves from the position
+// described by a FEN string until Stop is called or the context is cancelled
+//
+// onInfo is called with each line of search information from the goroutine reading
+// the engine's output. The search starts once the work queued before it has finished,
+// and other work waits until it is stopped. An empty fen starts from the initial position
+func (e *Engine) StartInfinite(ctx context.Context, fen, positionString string, onInfo InfoHandler) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.infinite != nil {
+		return ErrSearching
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	command, turnMult := positionCommand(fen, positionString)
+	ctx, cancel := context.WithCancel(ctx)
+	search := &infiniteSearch{
+		cancel: cancel,
+		evals:  make(chan []*MoveEval, 1),
+	}
+	e.infinite = search
+	// a search stopped by cancelling the context rather than by Stop is no longer running
+	context.AfterFunc(ctx, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.infinite == search {
+			e.infinite = nil
+		}
+	})
+	go func() {
+		var evals []*MoveEval
+		search.err = e.do(ctx, func() error {
+			// a crashed engine is restarted and carries on searching
+			return e.retry(func() error {
+				if ctx.Err() != nil {
+					return nil
+				}
+				err := e.SendCommand(command)
 				if err != nil {
-					return nil, err
+					return err
 				}
-				eval.Depth = depth
-			}
-			if word == "score" {
-				if dataLine[i+1] == "mate" {
-					eval.Mate = true
-					mateIn, err := strconv.Atoi(dataLine[i+2])
-					if err != nil {
-						return nil, err
-					}
-					eval.MateIn = mateIn
-				} else {
-					score, err := strconv.Atoi(dataLine[i+2])
-					if err != nil {
-						return nil, err
-					}
-					eval.Score = score * turnMult
+				err = e.SendCommand("go infinite")
+				if err != nil {
+					return err
 				}
+				response, err := e.readSearch(ctx, 0, turnMult, onInfo)
+				if err != nil && err != ctx.Err() {
+					return err
+				}
+				// parsed here as MultiPV is only read by the goroutine owning the engine
+				evals, err = e.parseResponse(response, turnMult)
+				return err
+			})
+		})
+		search.evals <- evals
+	}()
+	return nil
+}
+
+// Returns true if an infinite search is running
+func (e *Engine) Searching() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.infinite != nil
+}
+
+// Stops the infinite search, waiting for the engine to finish
+//
+// Returns the evals of the position when the search stopped
+func (e *Engine) Stop() ([]*MoveEval, error) {
+	e.mu.Lock()
+	search := e.infinite
+	e.infinite = nil
+	e.mu.Unlock()
+	if search == nil {
+		return nil, ErrNotSearching
+	}
+	search.cancel()
+	evals := <-search.evals
+	if search.err != nil {
+		return nil, search.err
+	}
+	return evals, nil
+}
+
+// Reads the lines sent by the engine while it searches, up to and including bestmove,
+// sending stop if the context is cancelled
+//
+// onInfo is called with each info line with a score as soon as it is read. The engine's
+// output is read up to bestmove even once the search is stopped, so the next search
+// doesn't read the end of this one. Returns an *EngineError if the engine exits, goes
+// longer than the timeout without sending a line or doesn't send bestmove within its
+// timeout of being stopped. A timeout of 0 waits until the search is stopped, as an
+// engine searching without a limit may stay silent once it reaches its maximum depth
+func (e *Engine) readSearch(ctx context.Context, timeout time.Duration, turnMult int, onInfo InfoHandler) ([]string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = timer.C
+	}
+	// restarts the timer, draining it first in case it fired while a line was read
+	reset := func(timeout time.Duration) {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(timeout)
+		deadline = timer.C
+	}
+	done := ctx.Done()
+	response := []string{}
+	for {
+		select {
+		case <-done:
+			done = nil
+			err := e.SendCommand("stop")
+			if err != nil {
+				return response, err
 			}
-			if word == "pv" {
-				eval.BestLine = dataLine[i+1:]
+			reset(e.Timeout)
+		case <-deadline:
+			return response, e.failure(ErrEngineTimeout)
+		case line, ok := <-e.proc.lines:
+			if !ok {
+				return response, e.failure(ErrEngineExited)
 			}
-			if word == "multipv" {
-				multiPV, err := strconv.Atoi(dataLine[i+1])
-				if err != nil {
-					return nil, err
-				}
-				eval.PVnum = multiPV
+			response = append(response, line)
+			if strings.HasPrefix(line, "bestmove") {
+				return response, ctx.Err()
+			}
+			if deadline != nil {
+				reset(e.Timeout)
+			}
+			if onInfo == nil {
+				continue
+			}
+			if info, err := parseInfo(line, turnMult); err == nil {
+				onInfo(info)
 			}
 		}
-		evals[i] = eval
+	}
+}
+
+// Parses the response from the engine
+//
+// The eval of each PV is the last info line sent for it with an exact score, or its last
+// bound if none was resolved. Positions with fewer legal
+// moves than MultiPV have fewer lines
+func (e *Engine) parseResponse(response []string, turnMult int) ([]*MoveEval, error) {
+	exact := map[int]*Info{}
+	bound := map[int]*Info{}
+	for _, line := range response {
+		info, err := parseInfo(line, turnMult)
+		if err != nil || info.PVnum < 1 || info.PVnum > e.MultiPV {
+			continue
+		}
+		if info.Exact() {
+			exact[info.PVnum] = info
+		} else {
+			bound[info.PVnum] = info
+		}
+	}
+	evals := []*MoveEval{}
+	for pvNum := 1; pvNum <= e.MultiPV; pvNum++ {
+		info, ok := exact[pvNum]
+		if !ok {
+			info, ok = bound[pvNum]
+		}
+		if !ok {
+			break
+		}
+		evals = append(evals, info.MoveEval())
+	}
+	if len(evals) == 0 {
+		return nil, ErrNoEval
 	}
 	return evals, nil
 }
 
+// Returns true if the engine can play the variant
+//
+// variant is the name used by UCI_Variant e.g. "atomic". Standard chess is
+// supported by every engine
+func (e *Engine) SupportsVariant(variant string) bool {
+	return variant == "chess" || slices.Contains(e.Variants, variant)
+}
+
+// Sets the variant the engine plays with UCI_Variant
+//
+// variant is the name used by UCI_Variant e.g. "atomic"
+func (e *Engine) SetVariant(variant string) error {
+	if !e.SupportsVariant(variant) {
+		return ErrVariantNotSupported
+	}
+	if len(e.Variants) == 0 {
+		// the engine only plays chess
+		return nil
+	}
+	return e.do(context.Background(), func() error {
+		return e.setOption("UCI_Variant", variant)
+	})
+}
+
+// Parses the variants from the UCI_Variant option sent in response to uci
+//
+// Expected input: "option name UCI_Variant type combo default chess var chess var atomic ..."
+func parseVariantOption(line string) ([]string, bool) {
+	option, ok := parseOption(line)
+	if !ok || !strings.EqualFold(option.Name, "UCI_Variant") {
+		return nil, false
+	}
+	return option.Vars, true
+}
+
+// Sets an option of the engine once the work queued before it has finished
+//
+// MoveTime and Depth limit the searches, other options are only sent if the engine advertised them
 func (e *Engine) ChangeOption(option, value string) error {
+	return e.do(context.Background(), func() error {
+		return e.changeOption(option, value)
+	})
+}
+
+func (e *Engine) changeOption(option, value string) error {
 	switch option {
 	case "MoveTime":
 		moveTime, err := strconv.Atoi(value)
@@ -134,6 +459,12 @@ func (e *Engine) ChangeOption(option, value string) error {
 			return err
 		}
 		e.Movetime = moveTime
+	case "Depth":
+		depth, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		e.Depth = depth
 	case "Threads":
 		threads, err := strconv.Atoi(value)
 		if err != nil {
@@ -155,6 +486,5 @@ func (e *Engine) ChangeOption(option, value string) error {
 	case "SyzygyPath":
 		e.SyzygyPath = value
 	}
-	e.SendCommand(fmt.Sprintf("setoption name %v value %v", option, value))
-	return nil
+	return e.setOption(option, value)
 }
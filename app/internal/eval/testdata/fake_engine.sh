@@ -0,0 +1,45 @@
+#!/bin/sh
+# A UCI engine that fails in the way given by FAKE_ENGINE_FAILURE during the first
+# search recorded in FAKE_ENGINE_LOG, searching normally once restarted
+#
+# FAKE_ENGINE_FAILURE is one of:
+#   handshake: exits before uciok
+#   exit:      exits during the search
+#   hang:      stops responding during the search
+while read -r command; do
+	echo "$command" >>"$FAKE_ENGINE_LOG"
+	case "$command" in
+	uci)
+		if [ "$FAKE_ENGINE_FAILURE" = handshake ]; then
+			echo "failed to load the network" >&2
+			exit 1
+		fi
+		echo "id name Fake Engine"
+		echo "option name Threads type spin default 1 min 1 max 8"
+		echo "option name Clear Hash type button"
+		echo "uciok"
+		;;
+	isready)
+		echo "readyok"
+		;;
+	go*)
+		if [ "$(grep -c '^go' "$FAKE_ENGINE_LOG")" = 1 ]; then
+			case "$FAKE_ENGINE_FAILURE" in
+			exit)
+				echo "info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 pv e2e4"
+				echo "segmentation fault" >&2
+				exit 139
+				;;
+			hang)
+				while read -r command; do :; done
+				;;
+			esac
+		fi
+		echo "info depth 1 seldepth 1 multipv 1 score cp 35 nodes 20 pv e2e4"
+		echo "bestmove e2e4"
+		;;
+	quit)
+		exit 0
+		;;
+	esac
+done
@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrNotUCI = errors.New("engine did not complete the uci handshake")
+
+// Types of the options a UCI engine can advertise
+type OptionType string
+
+const (
+	OptionCheck  OptionType = "check"  // true or false
+	OptionSpin   OptionType = "spin"   // an integer between Min and Max
+	OptionCombo  OptionType = "combo"  // one of Vars
+	OptionButton OptionType = "button" // an action without a value
+	OptionString OptionType = "string" // any text, "<empty>" if empty
+)
+
+// An option advertised by the engine in response to uci
+type Option struct {
+	Name    string
+	Type    OptionType
+	Default string
+	Min     int // spin only
+	Max     int // spin only
+	Vars    []string
+	Value   string // the value last set, or the default
+}
+
+// Parses an option sent in response to uci
+//
+// Expected input: "option name Skill Level type spin default 20 min 0 max 20"
+func parseOption(line string) (*Option, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "option" || fields[1] != "name" {
+		return nil, false
+	}
+	option := &Option{}
+	// the name and values may contain spaces so are read up to the next keyword
+	keyword := ""
+	words := []string{}
+	set := func() {
+		value := strings.Join(words, " ")
+		switch keyword {
+		case "name":
+			option.Name = value
+		case "type":
+			option.Type = OptionType(value)
+		case "default":
+			option.Default = value
+		case "min":
+			option.Min, _ = strconv.Atoi(value)
+		case "max":
+			option.Max, _ = strconv.Atoi(value)
+		case "var":
+			option.Vars = append(option.Vars, value)
+		}
+		words = []string{}
+	}
+	for _, field := range fields[1:] {
+		isKeyword := slices.Contains([]string{"type", "default", "min", "max", "var"}, field)
+		// names may contain keywords other than type e.g. "Minimum Thinking Time"
+		if isKeyword && (keyword != "name" || field == "type") {
+			set()
+			keyword = field
+			continue
+		}
+		if keyword == "" {
+			keyword = field
+			continue
+		}
+		words = append(words, field)
+	}
+	set()
+	if option.Name == "" || option.Type == "" {
+		return nil, false
+	}
+	option.Value = option.Default
+	return option, true
+}
+
+// Parses the engine's name or author sent in response to uci
+//
+// Expected input: "id name Stockfish 17"
+func parseID(line string) (key, value string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "id" {
+		return "", "", false
+	}
+	return fields[1], strings.Join(fields[2:], " "), true
+}
+
+// Sends uci to the engine, reading its id and options up to uciok
+func (e *Engine) handshake() error {
+	response, err := e.await("uci", "uciok")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotUCI, err)
+	}
+	for _, line := range response {
+		if key, value, ok := parseID(line); ok {
+			switch key {
+			case "name":
+				e.Name = value
+			case "author":
+				e.Author = value
+			}
+		}
+		if option, ok := parseOption(line); ok {
+			e.Options = append(e.Options, option)
+			if strings.EqualFold(option.Name, "UCI_Variant") {
+				e.Variants = option.Vars
+			}
+		}
+	}
+	return nil
+}
+
+// Waits for the engine to be ready for the next command
+func (e *Engine) waitReady() error {
+	_, err := e.await("isready", "readyok")
+	return err
+}
+
+// Sends a command to the engine, reading its responses until it sends the last line
+func (e *Engine) await(command, last string) ([]string, error) {
+	err := e.SendCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for {
+		response, err := e.ReadResponse()
+		lines = append(lines, response...)
+		if err != nil {
+			return lines, err
+		}
+		if response[len(response)-1] == last {
+			return lines, nil
+		}
+	}
+}
+
+// Returns the option the engine advertised with the name, or nil if it has none
+//
+// Option names aren't case sensitive
+func (e *Engine) Option(name string) *Option {
+	for _, option := range e.Options {
+		if strings.EqualFold(option.Name, name) {
+			return option
+		}
+	}
+	return nil
+}
+
+// Sets an option if the engine advertised it, during setup before the engine is shared
+func (e *Engine) setOption(name string, value any) error {
+	option := e.Option(name)
+	if option == nil {
+		return nil
+	}
+	option.Value = fmt.Sprint(value)
+	if option.Type == OptionButton {
+		return e.SendCommand(fmt.Sprintf("setoption name %v", option.Name))
+	}
+	return e.SendCommand(fmt.Sprintf("setoption name %v value %v", option.Name, value))
+}
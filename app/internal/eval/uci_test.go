@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOption(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected *Option
+	}{
+		{
+			"option name Threads type spin default 1 min 1 max 1024",
+			&Option{Name: "Threads", Type: OptionSpin, Default: "1", Min: 1, Max: 1024, Value: "1"},
+		},
+		{
+			"option name Skill Level type spin default 20 min 0 max 20",
+			&Option{Name: "Skill Level", Type: OptionSpin, Default: "20", Max: 20, Value: "20"},
+		},
+		{
+			"option name Minimum Thinking Time type spin default 20 min 0 max 5000",
+			&Option{Name: "Minimum Thinking Time", Type: OptionSpin, Default: "20", Max: 5000, Value: "20"},
+		},
+		{
+			"option name Ponder type check default false",
+			&Option{Name: "Ponder", Type: OptionCheck, Default: "false", Value: "false"},
+		},
+		{
+			"option name Clear Hash type button",
+			&Option{Name: "Clear Hash", Type: OptionButton},
+		},
+		{
+			"option name SyzygyPath type string default <empty>",
+			&Option{Name: "SyzygyPath", Type: OptionString, Default: "<empty>", Value: "<empty>"},
+		},
+		{
+			"option name Backend type combo default cuda-auto var cuda-auto var cuda var multiplexing var random",
+			&Option{Name: "Backend", Type: OptionCombo, Default: "cuda-auto", Vars: []string{"cuda-auto", "cuda", "multiplexing", "random"}, Value: "cuda-auto"},
+		},
+		{"option name Broken", nil},
+		{"id name Stockfish 17", nil},
+	}
+
+	for _, tt := range tests {
+		option, ok := parseOption(tt.line)
+		if ok != (tt.expected != nil) {
+			t.Errorf("Expected ok %v for %q, got %v", tt.expected != nil, tt.line, ok)
+			continue
+		}
+		if !reflect.DeepEqual(option, tt.expected) {
+			t.Errorf("Expected %+v for %q, got %+v", tt.expected, tt.line, option)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		line  string
+		key   string
+		value string
+		ok    bool
+	}{
+		{"id name Stockfish 17", "name", "Stockfish 17", true},
+		{"id author the Stockfish developers (see AUTHORS file)", "author", "the Stockfish developers (see AUTHORS file)", true},
+		{"id name", "", "", false},
+		{"uciok", "", "", false},
+	}
+
+	for _, tt := range tests {
+		key, value, ok := parseID(tt.line)
+		if key != tt.key || value != tt.value || ok != tt.ok {
+			t.Errorf("Expected %q %q %v for %q, got %q %q %v", tt.key, tt.value, tt.ok, tt.line, key, value, ok)
+		}
+	}
+}
+
+func TestOption(t *testing.T) {
+	eng := &Engine{Options: []*Option{{Name: "MultiPV", Type: OptionSpin}, {Name: "UCI_Chess960", Type: OptionCheck}}}
+	if option := eng.Option("multipv"); option == nil || option.Name != "MultiPV" {
+		t.Errorf("Expected MultiPV, got %v", option)
+	}
+	if option := eng.Option("Hash"); option != nil {
+		t.Errorf("Expected nil, got %v", option)
+	}
+}
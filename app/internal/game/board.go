@@ -110,7 +110,8 @@ func (b *Board) GetPieceAtSquare(file rune, rank int) (*Piece, error) {
 
 // Move a piece from one square to another
 // Doesn't check if the move is valid only if the square is occupied
-func (b *Board) MovePiece(move Move) error {
+// Only the board changes, a game's board is refilled from its position after each move
+func (b *Board) movePiece(move Move) error {
 	// Get the relevant pieces
 	fromPiece, err := b.GetPieceAtSquare(move.FromFile, move.FromRank)
 	if err != nil {
@@ -157,7 +158,8 @@ func (b *Board) MovePiece(move Move) error {
 }
 
 // Promote a pawn to another piece type
-func (b *Board) PromotePawn(file rune, rank int, pType PieceType) error {
+// As with movePiece the game the board belongs to is unchanged
+func (b *Board) promotePawn(file rune, rank int, pType PieceType) error {
 	p, err := b.GetPieceAtSquare(file, rank)
 	if err != nil {
 		return err
@@ -205,6 +207,35 @@ func (b *Board) Clone() *Board {
 	}
 }
 
+// Returns true if neither player has the pieces to checkmate
+//
+// This is the case with only kings and at most one knight or bishop,
+// or with only kings and bishops that are all on squares of the same colour
+func (b *Board) insufficientMaterial() bool {
+	minorPieces := 0
+	bishopSquareColors := map[int]bool{}
+	onlyBishops := true
+	for i, row := range b.Squares {
+		for j, p := range row {
+			if p == nil {
+				continue
+			}
+			switch p.PieceType {
+			case King:
+			case Bishop:
+				minorPieces++
+				bishopSquareColors[(i+j)%2] = true
+			case Knight:
+				minorPieces++
+				onlyBishops = false
+			default:
+				return false
+			}
+		}
+	}
+	return minorPieces <= 1 || (onlyBishops && len(bishopSquareColors) == 1)
+}
+
 // converts 1-8 to a-h
 func intToFile(i int) rune {
 	return rune(i+'a') - 1
@@ -57,7 +57,7 @@ func TestGetPieceAtSquareAfterMove(t *testing.T) {
 	b := g.Board
 	piece, _ := b.GetPieceAtSquare('e', 2)
 	move := Move{FromFile: 'e', FromRank: 2, ToFile: 'e', ToRank: 4}
-	b.MovePiece(move)
+	b.movePiece(move)
 
 	tests := []struct {
 		file  rune
@@ -117,7 +117,7 @@ func TestMovePiece(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		err := b.MovePiece(tt.move)
+		err := b.movePiece(tt.move)
 		if err != nil && tt.err == nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -141,7 +141,7 @@ func TestMovePieceCapture(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		err := b.MovePiece(tt.move)
+		err := b.movePiece(tt.move)
 		if err != nil && tt.err == nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -178,7 +178,7 @@ func TestPromotePawn(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		err := b.PromotePawn(tt.file, tt.rank, tt.pType)
+		err := b.promotePawn(tt.file, tt.rank, tt.pType)
 		if err != nil && tt.err == nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -194,7 +194,7 @@ func TestGetLocation(t *testing.T) {
 	qd8, _ := b.GetPieceAtSquare('d', 8)
 	pe2, _ := b.GetPieceAtSquare('e', 2)
 	pa7, _ := b.GetPieceAtSquare('a', 7)
-	b.MovePiece(Move{FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 7, Capture: 'x'})
+	b.movePiece(Move{FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 7, Capture: 'x'})
 	tests := []struct {
 		piece *Piece
 		file  rune
@@ -0,0 +1,79 @@
+package game
+
+// The ways to castle in the order of the castling field of a FEN
+var castlingSides = [4]struct {
+	color    string
+	kingside bool
+}{
+	{"white", true},
+	{"white", false},
+	{"black", true},
+	{"black", false},
+}
+
+// Returns the rank the pieces of the colour start on
+func backRank(color string) int {
+	if color == "black" {
+		return 8
+	}
+	return 1
+}
+
+// Returns the files the king and rook finish on after castling
+func castlingDestinations(castle string) (kingFile, rookFile rune) {
+	if castle == "long" {
+		return 'c', 'd'
+	}
+	return 'g', 'f'
+}
+
+// Returns the files of the king and rook that can castle to the given side
+//
+// In Chess960 the king and rooks can start on any file so the unmoved rook
+// furthest from the king on that side is used
+// ok is false if the colour can't castle to that side
+func (b *Board) castlingFiles(color string, kingside bool) (kingFile, rookFile rune, ok bool) {
+	rank := b.Squares[backRank(color)-1]
+	king := -1
+	for i, p := range rank {
+		if p != nil && p.PieceType == King && p.Color == color && !p.Moved {
+			king = i
+		}
+	}
+	if king < 0 {
+		return 0, 0, false
+	}
+	isRook := func(i int) bool {
+		p := rank[i]
+		return p != nil && p.PieceType == Rook && p.Color == color && !p.Moved
+	}
+	if kingside {
+		for i := 7; i > king; i-- {
+			if isRook(i) {
+				return intToFile(king + 1), intToFile(i + 1), true
+			}
+		}
+	} else {
+		for i := 0; i < king; i++ {
+			if isRook(i) {
+				return intToFile(king + 1), intToFile(i + 1), true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Returns true if castling would move a king or rook from a square other than
+// the ones they start on in standard chess
+func (b *Board) isChess960() bool {
+	for _, side := range castlingSides {
+		kingFile, rookFile, ok := b.castlingFiles(side.color, side.kingside)
+		if !ok {
+			continue
+		}
+		if kingFile != 'e' || (side.kingside && rookFile != 'h') || (!side.kingside && rookFile != 'a') {
+			return true
+		}
+	}
+	return false
+}
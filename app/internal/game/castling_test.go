@@ -0,0 +1,141 @@
+package game
+
+import "testing"
+
+func TestCastlingRightsFEN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+		chess960 bool
+	}{
+		// Shredder-FEN for the standard position
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", false},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9", true},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9", true},
+		// X-FEN gives the file of a rook with another rook beyond it
+		{"4k3/8/8/8/8/8/8/RR2K3 w B - 0 1", "4k3/8/8/8/8/8/8/RR2K3 w B - 0 1", true},
+		{"4k3/8/8/8/8/8/8/RR2K3 w Q - 0 1", "4k3/8/8/8/8/8/8/RR2K3 w Q - 0 1", false},
+		// Rights without a rook are ignored
+		{"4k3/8/8/8/8/8/8/4K3 w Kq - 0 1", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		g, err := NewGameFromFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		if g.FEN() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.fen, tt.expected, g.FEN())
+		}
+		if g.Chess960 != tt.chess960 {
+			t.Errorf("%s: expected Chess960 %t, got %t", tt.fen, tt.chess960, g.Chess960)
+		}
+	}
+}
+
+func TestCastleChess960(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		uci      bool
+		expected string
+	}{
+		{
+			name:     "queenside next to the rook",
+			fen:      "4k3/8/8/8/8/8/8/RK5R w KQ - 0 1",
+			move:     "O-O-O",
+			expected: "4k3/8/8/8/8/8/8/2KR3R b - - 1 1",
+		},
+		{
+			name:     "kingside across the board",
+			fen:      "4k3/8/8/8/8/8/8/RK5R w KQ - 0 1",
+			move:     "O-O",
+			expected: "4k3/8/8/8/8/8/8/R4RK1 b - - 1 1",
+		},
+		{
+			name:     "king captures its own rook",
+			fen:      "4k3/8/8/8/8/8/8/RK5R w KQ - 0 1",
+			move:     "b1a1",
+			uci:      true,
+			expected: "4k3/8/8/8/8/8/8/2KR3R b - - 1 1",
+		},
+		{
+			name:     "king moving one square is not castling",
+			fen:      "4k3/8/8/8/8/8/8/RK5R w KQ - 0 1",
+			move:     "b1c1",
+			uci:      true,
+			expected: "4k3/8/8/8/8/8/8/R1K4R b - - 1 1",
+		},
+		{
+			name:     "king and rook swap",
+			fen:      "4k3/8/8/8/8/8/8/5KR1 w K - 0 1",
+			move:     "f1g1",
+			uci:      true,
+			expected: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+		},
+		{
+			name:     "king already on its square",
+			fen:      "4k3/8/8/8/8/8/8/6KR w K - 0 1",
+			move:     "O-O",
+			expected: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+		},
+		{
+			name:     "black",
+			fen:      "rk5r/8/8/8/8/8/8/4K3 b kq - 0 1",
+			move:     "O-O-O",
+			expected: "2kr3r/8/8/8/8/8/8/4K3 w - - 1 2",
+		},
+	}
+	for _, tt := range tests {
+		g, err := NewGameFromFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		hash := g.Hash()
+		if tt.uci {
+			_, err = g.MoveUCI(tt.move)
+		} else {
+			_, err = g.Move(tt.move)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g.FEN() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, g.FEN())
+		}
+		if g.Hash() != boardHash(g) {
+			t.Errorf("%s: incremental hash doesn't match", tt.name)
+		}
+		_, err = g.Undo()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g.FEN() != tt.fen || g.Hash() != hash {
+			t.Errorf("%s: expected undo to restore %s, got %s", tt.name, tt.fen, g.FEN())
+		}
+	}
+}
+
+func TestCastleUCINotation(t *testing.T) {
+	g, err := NewGameFromFEN("4k3/8/8/8/8/8/8/RK5R w KQ - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"short": "b1h1", "long": "b1a1"}
+	for _, move := range g.GetPossibleMoves() {
+		if move.Castle == "" {
+			continue
+		}
+		uci, err := move.UCInotation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uci != expected[move.Castle] {
+			t.Errorf("Expected %s for %s castling, got %s", expected[move.Castle], move.Castle, uci)
+		}
+		delete(expected, move.Castle)
+	}
+	if len(expected) != 0 {
+		t.Errorf("Expected castling moves %v", expected)
+	}
+}
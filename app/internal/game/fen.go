@@ -0,0 +1,307 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFEN = errors.New("invalid FEN")
+
+// FEN of the initial game state
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Create a new game from a position in Forsyth-Edwards Notation
+//
+// The halfmove clock and fullmove number may be omitted
+// e.g. "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+//
+// Three-check positions have the checks each side has left to give after the
+// en passant square e.g. "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+func NewGameFromFEN(fen string) (*Game, error) {
+	fields := strings.Fields(fen)
+	var checks string
+	if len(fields) == 5 || len(fields) == 7 {
+		checks = fields[4]
+		fields = append(fields[:4], fields[5:]...)
+	}
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, ErrInvalidFEN
+	}
+	board, err := parsePlacement(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{
+		Board:          board,
+		MoveHistory:    []Move{},
+		HalfmoveClock:  0,
+		FullmoveNumber: 1,
+	}
+	// Side to move
+	switch fields[1] {
+	case "w":
+		g.Turn = "white"
+	case "b":
+		g.Turn = "black"
+	default:
+		return nil, ErrInvalidFEN
+	}
+	// Castling rights
+	err = board.setCastlingRights(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	g.Chess960 = board.isChess960()
+	g.pos = newPosition(g)
+	// En passant target square
+	if fields[3] != "-" {
+		epRank := 6
+		if g.Turn == "black" {
+			epRank = 3
+		}
+		if len(fields[3]) != 2 ||
+			fileToInt(rune(fields[3][0])) < 1 || fileToInt(rune(fields[3][0])) > 8 ||
+			int(fields[3][1]-'0') != epRank {
+			return nil, ErrInvalidFEN
+		}
+		g.pos.epSquare = square(rune(fields[3][0]), epRank)
+	}
+	// Remaining checks
+	if checks != "" {
+		g.Variant = ThreeCheck
+		g.pos.checks, err = parseRemainingChecks(checks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Clocks
+	if len(fields) == 6 {
+		g.HalfmoveClock, err = strconv.Atoi(fields[4])
+		if err != nil || g.HalfmoveClock < 0 {
+			return nil, ErrInvalidFEN
+		}
+		g.FullmoveNumber, err = strconv.Atoi(fields[5])
+		if err != nil || g.FullmoveNumber < 1 {
+			return nil, ErrInvalidFEN
+		}
+	}
+	g.positions = []uint64{g.positionKey()}
+	return g, nil
+}
+
+// Returns the current position in Forsyth-Edwards Notation
+func (g *Game) FEN() string {
+	turn := "w"
+	if g.Turn == "black" {
+		turn = "b"
+	}
+	enPassant := "-"
+	if file, rank, ok := g.enPassantSquare(); ok {
+		enPassant = fmt.Sprintf("%c%d", file, rank)
+	}
+	if g.Variant == ThreeCheck {
+		enPassant += fmt.Sprintf(" %d+%d", checksToWin-g.pos.checks[white], checksToWin-g.pos.checks[black])
+	}
+	return fmt.Sprintf(
+		"%s %s %s %s %d %d",
+		g.Board.placement(),
+		turn,
+		g.Board.castlingRights(),
+		enPassant,
+		g.HalfmoveClock,
+		g.FullmoveNumber,
+	)
+}
+
+// Parses the checks white and black have left to give in three-check e.g. "3+2"
+//
+// Returns the checks each side has given
+func parseRemainingChecks(field string) ([2]int, error) {
+	var checks [2]int
+	remaining := strings.Split(field, "+")
+	if len(remaining) != 2 {
+		return checks, ErrInvalidFEN
+	}
+	for i, r := range remaining {
+		n, err := strconv.Atoi(r)
+		if err != nil || n < 0 || n > checksToWin {
+			return checks, ErrInvalidFEN
+		}
+		checks[i] = checksToWin - n
+	}
+	return checks, nil
+}
+
+// Parses the piece placement field of a FEN into a board
+//
+// Each side must have one king, and pawns can't be on the first or last rank
+func parsePlacement(placement string) (*Board, error) {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return nil, ErrInvalidFEN
+	}
+	squares := [8][8]*Piece{}
+	kings := map[string]int{}
+	for i, rankStr := range ranks {
+		rank := 7 - i
+		file := 0
+		for _, r := range rankStr {
+			if r >= '1' && r <= '8' {
+				file += int(r - '0')
+				continue
+			}
+			if file > 7 {
+				return nil, ErrInvalidFEN
+			}
+			p, err := pieceFromFENSymbol(r)
+			if err != nil {
+				return nil, err
+			}
+			if p.PieceType == Pawn && (rank == 0 || rank == 7) {
+				return nil, ErrInvalidFEN
+			}
+			if p.PieceType == King {
+				kings[p.Color]++
+			}
+			squares[rank][file] = p
+			file++
+		}
+		if file != 8 {
+			return nil, ErrInvalidFEN
+		}
+	}
+	if kings["white"] != 1 || kings["black"] != 1 {
+		return nil, ErrInvalidFEN
+	}
+	return CustomBoard(squares), nil
+}
+
+// Returns the piece placement field of a FEN for the board
+func (b *Board) placement() string {
+	var sb strings.Builder
+	for i := 7; i >= 0; i-- {
+		empty := 0
+		for _, p := range b.Squares[i] {
+			if p == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteRune(p.getFENSymbol())
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if i > 0 {
+			sb.WriteRune('/')
+		}
+	}
+	return sb.String()
+}
+
+// Marks the kings and rooks as moved or unmoved to match the castling field of a FEN
+//
+// Accepts KQkq, where the rook furthest from the king on that side castles,
+// as well as the rook files used by X-FEN and Shredder-FEN for Chess960
+func (b *Board) setCastlingRights(rights string) error {
+	for _, row := range b.Squares {
+		for _, p := range row {
+			if p != nil && (p.PieceType == King || p.PieceType == Rook) {
+				p.Moved = true
+			}
+		}
+	}
+	if rights == "-" {
+		return nil
+	}
+	for _, r := range rights {
+		color := "white"
+		if r >= 'a' && r <= 'z' {
+			color = "black"
+			r -= 'a' - 'A'
+		}
+		if r != 'K' && r != 'Q' && (r < 'A' || r > 'H') {
+			return ErrInvalidFEN
+		}
+		rank := b.Squares[backRank(color)-1]
+		king := -1
+		for i, p := range rank {
+			if p != nil && p.PieceType == King && p.Color == color {
+				king = i
+			}
+		}
+		// Rights that the pieces can't support are ignored
+		if king < 0 {
+			continue
+		}
+		isRook := func(i int) bool {
+			p := rank[i]
+			return p != nil && p.PieceType == Rook && p.Color == color
+		}
+		rook := -1
+		switch r {
+		case 'K':
+			for i := 7; i > king && rook < 0; i-- {
+				if isRook(i) {
+					rook = i
+				}
+			}
+		case 'Q':
+			for i := 0; i < king && rook < 0; i++ {
+				if isRook(i) {
+					rook = i
+				}
+			}
+		default:
+			if i := int(r - 'A'); i != king && isRook(i) {
+				rook = i
+			}
+		}
+		if rook < 0 {
+			continue
+		}
+		rank[king].Moved = false
+		rank[rook].Moved = false
+	}
+	return nil
+}
+
+// Returns the castling field of a FEN for the board
+//
+// Uses X-FEN, so the file of the rook is only given in Chess960 positions
+// where another rook stands further from the king on the same side
+func (b *Board) castlingRights() string {
+	rights := ""
+	for _, side := range castlingSides {
+		_, rookFile, ok := b.castlingFiles(side.color, side.kingside)
+		if !ok {
+			continue
+		}
+		symbol := 'K'
+		if !side.kingside {
+			symbol = 'Q'
+		}
+		// the rook is ambiguous if there is another rook beyond it
+		rank := b.Squares[backRank(side.color)-1]
+		for i := range rank {
+			file := intToFile(i + 1)
+			beyond := (side.kingside && file > rookFile) || (!side.kingside && file < rookFile)
+			if p := rank[i]; beyond && p != nil && p.PieceType == Rook && p.Color == side.color {
+				symbol = rookFile - 'a' + 'A'
+			}
+		}
+		if side.color == "black" {
+			symbol += 'a' - 'A'
+		}
+		rights += string(symbol)
+	}
+	if rights == "" {
+		return "-"
+	}
+	return rights
+}
@@ -0,0 +1,151 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestNewGameFromFEN(t *testing.T) {
+	tests := []struct {
+		fen string
+		err error
+	}{
+		{StartingFEN, nil},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", nil},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", nil},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -", nil},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 12 40", nil},
+		// Failing cases
+		{"", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/7/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQxq - 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", ErrInvalidFEN},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", ErrInvalidFEN},
+		{"P3k3/8/8/8/8/8/8/4K3 w - - 0 1", ErrInvalidFEN},
+		{"4k3/8/8/8/8/8/8/p3K3 b - - 0 1", ErrInvalidFEN},
+		{"8/8/8/8/8/8/8/8 w - - 0 1", ErrInvalidFEN},
+		{"4k3/8/8/8/8/8/8/8 w - - 0 1", ErrInvalidFEN},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", ErrInvalidFEN},
+		{"3kk3/8/8/8/8/8/8/4K3 w - - 0 1", ErrInvalidFEN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != tt.err {
+				t.Errorf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if err == nil && g == nil {
+				t.Errorf("Expected game, got nil")
+			}
+		})
+	}
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+	}{
+		{StartingFEN, StartingFEN},
+		{
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		},
+		{
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		},
+		{
+			"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 3 17",
+			"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 3 17",
+		},
+		// Clocks default when omitted
+		{
+			"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+			"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		},
+		// Castling rights the pieces can't support are dropped
+		{
+			"4k3/8/8/8/8/8/8/4K2R w KQkq - 0 1",
+			"4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			actual := g.FEN()
+			if actual != tt.expected {
+				t.Errorf("Expected: %s, got: %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	g := NewGame()
+	tests := []struct {
+		move     string
+		expected string
+	}{
+		{"e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{"c5", "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"},
+		{"Nf3", "rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+		{"Nc6", "r1bqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"},
+		{"Be2", "r1bqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPPBPPP/RNBQK2R b KQkq - 3 3"},
+		{"Rb8", "1rbqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPPBPPP/RNBQK2R w KQk - 4 4"},
+		{"O-O", "1rbqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPPBPPP/RNBQ1RK1 b k - 5 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			_, err := g.Move(tt.move)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			actual := g.FEN()
+			if actual != tt.expected {
+				t.Errorf("Expected: %s, got: %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFENEnPassant(t *testing.T) {
+	g, err := NewGameFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = g.Move("exf6")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "rnbqkbnr/ppp1p1pp/5P2/3p4/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3"
+	if g.FEN() != expected {
+		t.Errorf("Expected: %s, got: %s", expected, g.FEN())
+	}
+	captured := g.Board.GetCapturedByColour("white")
+	if len(captured) != 1 || captured[0].PieceType != Pawn {
+		t.Errorf("Expected captured pawn, got %v", captured)
+	}
+	// No en passant capture without the target square
+	g, err = NewGameFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, move := range []string{"exd6", "exf6"} {
+		_, err = g.Move(move)
+		if err != ErrInvalidMove {
+			t.Errorf("Expected: %v, got: %v", ErrInvalidMove, err)
+		}
+	}
+}
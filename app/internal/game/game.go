@@ -4,32 +4,79 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"math/bits"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
-var ErrInvalidMove = errors.New("invalid move")
+var (
+	ErrInvalidMove  = errors.New("invalid move")
+	ErrNoMoveToUndo = errors.New("no move to undo")
+)
+
+// Matches a move in UCI notation e.g. e2e4 or e7e8q
+var uciPattern = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][nbrqNBRQ]?$`)
 
 type Game struct {
-	Board       *Board
-	Turn        string
-	MoveHistory []Move
+	Board          *Board // the pieces on each square, filled from the position after every move
+	Turn           string
+	MoveHistory    []Move
+	HalfmoveClock  int  // halfmoves since the last capture or pawn move
+	FullmoveNumber int  // starts at 1 and is incremented after black's move
+	Chess960       bool // the king or rooks that can castle start on non-standard squares
+	Variant        Variant
+	pos            *position    // the position moves are generated and played in
+	legal          []posMove    // legal moves in the position, nil until generated
+	positions      []uint64     // key of every position reached, used to detect repetition
+	undoStack      []undoRecord // state needed to undo each move in the move history
+}
+
+// The state before a move that can't be recovered from the move itself
+type undoRecord struct {
+	move           posMove
+	undo           posUndo
+	captured       int // pieces the move took off the board
+	halfmoveClock  int
+	fullmoveNumber int
 }
 
 // Create a new game
 func NewGame() *Game {
-	return &Game{
-		Board:       NewBoard(),
-		Turn:        "white",
-		MoveHistory: []Move{},
+	g := &Game{
+		Board:          NewBoard(),
+		Turn:           "white",
+		MoveHistory:    []Move{},
+		HalfmoveClock:  0,
+		FullmoveNumber: 1,
 	}
+	g.pos = newPosition(g)
+	g.positions = []uint64{g.positionKey()}
+	return g
 }
 
 // Converts a slice of moves to long algebraic notation
 // by playing them and getting the long algebraic notation of the move history
 func ConvertNotation(moves []string) ([]string, error) {
+	return ConvertNotationFromFEN("", moves)
+}
+
+// Converts a slice of moves played from the position described by a FEN string
+// to long algebraic notation
+//
+// An empty fen starts from the initial position
+func ConvertNotationFromFEN(fen string, moves []string) ([]string, error) {
 	g := NewGame()
+	if fen != "" {
+		var err error
+		g, err = NewGameFromFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+	}
 	err := g.Moves(moves)
 	if err != nil {
 		return nil, err
@@ -45,12 +92,15 @@ func (g *Game) Play() {
 		args := strings.Split(strings.ToLower(userInput), " ")
 		switch args[0] {
 		case "help":
-			fmt.Println("Type move in short algebraic notation to play it (e.g. e4)")
+			fmt.Println("Type move in algebraic or UCI notation to play it (e.g. e4, e2e4)")
 			fmt.Println("Type 'quit' to exit the game")
 			fmt.Println("Type 'move_history' to see the move history")
 			fmt.Println("      Add '--short' to see the move history in short algebraic notation")
 			fmt.Println("Type 'possible_moves' to see all possible moves")
 			fmt.Println("Type 'new_game' to start a new game")
+			fmt.Println("Type 'fen' to see the current position in FEN")
+			fmt.Println("Type 'perft <depth>' to count the positions reachable in <depth> moves")
+			fmt.Println("      Use 'divide <depth>' to see the count after each move")
 			continue
 		case "quit":
 			return
@@ -79,6 +129,38 @@ func (g *Game) Play() {
 		case "new_game":
 			g.NewGame()
 			continue
+		case "fen":
+			fmt.Println(g.FEN())
+			continue
+		case "undo":
+			_, err := g.Undo()
+			if err != nil {
+				fmt.Println(err)
+			}
+			continue
+		case "perft", "divide":
+			if len(args) < 2 {
+				fmt.Println("Provide a depth e.g. 'perft 3'")
+				continue
+			}
+			depth, err := strconv.Atoi(args[1])
+			if err != nil || depth < 1 {
+				fmt.Println("Invalid depth")
+				continue
+			}
+			if args[0] == "divide" {
+				divide := g.Divide(depth)
+				moves := make([]string, 0, len(divide))
+				for move := range divide {
+					moves = append(moves, move)
+				}
+				sort.Strings(moves)
+				for _, move := range moves {
+					fmt.Printf("%s: %d\n", move, divide[move])
+				}
+			}
+			fmt.Printf("Nodes: %d\n", g.Perft(depth))
+			continue
 		default:
 			_, err := g.Move(userInput)
 			if err == ErrInvalidMove {
@@ -107,14 +189,21 @@ func (g *Game) logPossibleMoves() {
 	fmt.Println(printString[:len(printString)-2])
 }
 
-// Takes a move string in algebraic notation,
+// Takes a move string in short algebraic notation (e.g. Nf3), long algebraic
+// notation (e.g. Ng1f3, Ng1-f3, e7xd8=Q) or UCI notation (e.g. g1f3, e7d8q),
 // checks if it is valid and moves the piece
 func (g *Game) Move(moveStr string) (Move, error) {
+	if uciPattern.MatchString(moveStr) {
+		return g.MoveUCI(moveStr)
+	}
 	// Check if the move string is valid
 	move, err := parseRegex(moveStr)
 	if err != nil {
 		return Move{}, err
 	}
+	if move.Castle == "" && move.FromFile != 0 && move.FromRank != 0 {
+		return g.moveFromSquare(move)
+	}
 	// Get all possible moves for the current player
 	possibleMoves := g.GetPossibleMoves()
 	// Find the move that corresponds to the given move and play it
@@ -122,188 +211,385 @@ func (g *Game) Move(moveStr string) (Move, error) {
 	if err != nil {
 		return Move{}, err
 	}
-	// inherit check status from the move
-	if move.CheckStatus != 0 {
-		correspondingMove.CheckStatus = move.CheckStatus
-	}
-	if correspondingMove.Castle == "" {
-		err = g.Board.MovePiece(correspondingMove)
-	} else {
-		err = g.Castle(correspondingMove.Castle)
-	}
+	// the check status is determined by playing the move, not by the move string
+	return g.play(correspondingMove)
+}
+
+// Takes a move in UCI notation (e.g. e2e4, e7e8q),
+// checks if it is valid and moves the piece
+func (g *Game) MoveUCI(uci string) (Move, error) {
+	move, err := g.findUCIMove(uci)
 	if err != nil {
 		return Move{}, err
 	}
-	g.MoveHistory = append(g.MoveHistory, correspondingMove)
-	g.changeTurn()
-	return correspondingMove, nil
+	return g.play(move)
 }
 
-// Takes a slice of move strings in algebraic notation and plays them
-func (g *Game) Moves(moveStrs []string) error {
-	for _, moveStr := range moveStrs {
-		_, err := g.Move(moveStr)
+// Finds the possible move given in UCI notation without playing it
+func (g *Game) findUCIMove(uci string) (Move, error) {
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, ErrInvalidMove
+	}
+	var promotion rune
+	if len(uci) == 5 {
+		promotion = unicode.ToUpper(rune(uci[4]))
+	}
+	return g.findMove(rune(uci[0]), int(uci[1]-'0'), rune(uci[2]), int(uci[3]-'0'), promotion)
+}
+
+// Plays a line of moves in UCI notation, such as an engine's principal variation,
+// from the current position and returns the moves along with their SAN
+//
+// The game is left in its current position. The line is cut short at the first
+// move that can't be played, returning the moves before it along with the error
+func (g *Game) UCILine(line []string) ([]Move, []string, error) {
+	moves := []Move{}
+	sans := []string{}
+	defer func() {
+		for range moves {
+			g.Undo()
+		}
+	}()
+	for _, uci := range line {
+		move, err := g.findUCIMove(uci)
 		if err != nil {
-			return err
+			return moves, sans, err
+		}
+		san, err := g.SAN(move)
+		if err != nil {
+			return moves, sans, err
+		}
+		played, err := g.play(move)
+		if err != nil {
+			return moves, sans, err
 		}
+		moves = append(moves, played)
+		sans = append(sans, san)
 	}
-	return nil
+	return moves, sans, nil
 }
 
-// Get all possible moves for the current player
-func (g *Game) GetPossibleMoves() []Move {
-	possibleMoves := []Move{}
-	for _, row := range g.Board.Squares {
-		for _, p := range row {
-			if p != nil && p.Color == g.Turn {
-				possibleMoves = append(possibleMoves, p.GetPossibleMoves(g)...)
-			}
+// Converts a line of moves in UCI notation played from the current position to SAN
+// with move numbers e.g. ["12. Nf3", "Nc6", "13. d4"], or ["12... Nc6", "13. d4"]
+// if black is to move
+//
+// The line is cut short at the first move that can't be played, returning the
+// moves before it along with the error
+func (g *Game) UCILineToSAN(line []string) ([]string, error) {
+	_, sans, err := g.UCILine(line)
+	numbered := make([]string, len(sans))
+	number, whiteToMove := g.FullmoveNumber, g.Turn == "white"
+	for i, san := range sans {
+		switch {
+		case whiteToMove:
+			numbered[i] = fmt.Sprintf("%d. %s", number, san)
+		case i == 0:
+			numbered[i] = fmt.Sprintf("%d... %s", number, san)
+			number++
+		default:
+			numbered[i] = san
+			number++
 		}
+		whiteToMove = !whiteToMove
 	}
-	possibleMoves = append(possibleMoves, g.getPossibleCastles()...)
-	return possibleMoves
+	return numbered, err
 }
 
-func (g *Game) getPossibleCastles() []Move {
-	possibleMoves := []Move{}
-	homeRank := 1
-	if g.Turn == "black" {
-		homeRank = 8
-	}
-	king, err := g.Board.GetPieceAtSquare('e', homeRank)
+// Plays a move that gives the square the piece moves from, as in long algebraic notation
+//
+// The move is found from the squares alone, so a missing or extra capture or check
+// symbol doesn't make it invalid, but the piece must match the one on the square
+func (g *Game) moveFromSquare(move Move) (Move, error) {
+	piece, err := g.Board.GetPieceAtSquare(move.FromFile, move.FromRank)
 	if err != nil {
-		return possibleMoves
+		return Move{}, err
 	}
-	// Kingside castle
-	kingsideRook, err := g.Board.GetPieceAtSquare('h', homeRank)
-	if err != nil {
-		return possibleMoves
+	if piece == nil {
+		return Move{}, ErrInvalidMove
 	}
-	fSquare, err := g.Board.GetPieceAtSquare('f', homeRank)
-	if err != nil {
-		return possibleMoves
+	if symbol, _ := piece.getSymbol(); symbol != move.Piece {
+		return Move{}, ErrInvalidMove
 	}
-	gSquare, err := g.Board.GetPieceAtSquare('g', homeRank)
+	correspondingMove, err := g.findMove(move.FromFile, move.FromRank, move.ToFile, move.ToRank, move.Promotion)
 	if err != nil {
-		return possibleMoves
-	}
-	if kingsideRook != nil && kingsideRook.PieceType == Rook &&
-		kingsideRook.Color == g.Turn && !kingsideRook.Moved &&
-		king != nil && king.PieceType == King &&
-		king.Color == g.Turn && !king.Moved &&
-		fSquare == nil && gSquare == nil {
-		possibleMoves = append(possibleMoves, Move{
-			FromRank: homeRank,
-			Castle:   "short",
-		})
-	}
-	// Queenside castle
-	queensideRook, err := g.Board.GetPieceAtSquare('a', homeRank)
-	if err != nil {
-		return possibleMoves
+		return Move{}, err
 	}
-	bSquare, err := g.Board.GetPieceAtSquare('b', homeRank)
-	if err != nil {
-		return possibleMoves
+	return g.play(correspondingMove)
+}
+
+// Takes a move such as one from the move history of another game,
+// checks if it is valid and moves the piece
+func (g *Game) PlayMove(move Move) (Move, error) {
+	var correspondingMove Move
+	var err error
+	if move.Castle != "" {
+		correspondingMove, err = getCorrespondingMove(Move{Castle: move.Castle}, g.GetPossibleMoves())
+	} else {
+		correspondingMove, err = g.findMove(move.FromFile, move.FromRank, move.ToFile, move.ToRank, move.Promotion)
 	}
-	cSquare, err := g.Board.GetPieceAtSquare('c', homeRank)
 	if err != nil {
-		return possibleMoves
+		return Move{}, err
 	}
-	dSquare, err := g.Board.GetPieceAtSquare('d', homeRank)
-	if err != nil {
-		return possibleMoves
-	}
-	if queensideRook != nil && queensideRook.PieceType == Rook &&
-		queensideRook.Color == g.Turn && !queensideRook.Moved &&
-		king != nil && king.PieceType == King &&
-		king.Color == g.Turn && !king.Moved &&
-		bSquare == nil && cSquare == nil && dSquare == nil {
-		possibleMoves = append(possibleMoves, Move{
-			FromRank: homeRank,
-			Castle:   "long",
-		})
+	return g.play(correspondingMove)
+}
+
+// Finds the possible move of a piece from one square to another
+//
+// Castling is given as the king moving to its destination, or as the king
+// capturing its own rook as in Chess960. Other moves take precedence
+// as the king may only move one square when castling in Chess960
+func (g *Game) findMove(fromFile rune, fromRank int, toFile rune, toRank int, promotion rune) (Move, error) {
+	castles := []Move{}
+	for _, move := range g.GetPossibleMoves() {
+		if move.Castle != "" {
+			castles = append(castles, move)
+			continue
+		}
+		if move.FromFile == fromFile && move.FromRank == fromRank &&
+			move.ToFile == toFile && move.ToRank == toRank &&
+			move.Promotion == promotion {
+			return move, nil
+		}
 	}
-	return possibleMoves
+	for _, move := range castles {
+		kingFile, _ := castlingDestinations(move.Castle)
+		if move.FromFile == fromFile && move.FromRank == fromRank &&
+			(toFile == move.ToFile || toFile == kingFile) && toRank == move.FromRank && promotion == 0 {
+			return move, nil
+		}
+	}
+	return Move{}, ErrInvalidMove
 }
 
-func (g *Game) Castle(castletype string) error {
-	homeRank := 1
-	var err error
-	b := g.Board
-	king, err := b.GetPieceAtSquare('e', homeRank)
-	if err != nil {
-		return err
+// Plays a move from the list of possible moves
+//
+// Returns the move with its check status set
+func (g *Game) play(move Move) (Move, error) {
+	pm, ok := g.findPosMove(move)
+	if !ok {
+		return Move{}, ErrInvalidMove
+	}
+	pos := g.position()
+	// castling is stored with the files the king and rook start on
+	move = pos.toMove(pm)
+	us := pos.turn
+	record := undoRecord{
+		move:           pm,
+		halfmoveClock:  g.HalfmoveClock,
+		fullmoveNumber: g.FullmoveNumber,
+	}
+	record.undo = pos.makeMove(pm)
+	g.legal = nil
+	captured := capturedPieces(pm, record.undo, us)
+	record.captured = len(captured)
+	g.Board.captured = append(g.Board.captured, captured...)
+	pos.fillBoard(g.Board)
+	g.undoStack = append(g.undoStack, record)
+	if pm.piece == Pawn || pm.flags&moveCapture != 0 {
+		g.HalfmoveClock = 0
+	} else {
+		g.HalfmoveClock++
 	}
 	if g.Turn == "black" {
-		homeRank = 8
-	}
-	if castletype == "short" {
-		err = b.MovePiece(Move{
-			Piece:    'K',
-			FromFile: 'e',
-			FromRank: homeRank,
-			ToFile:   'g',
-			ToRank:   homeRank,
-		})
-		if err != nil {
-			return err
+		g.FullmoveNumber++
+	}
+	g.changeTurn()
+	move.CheckStatus = pos.checkStatus()
+	g.MoveHistory = append(g.MoveHistory, move)
+	g.positions = append(g.positions, g.positionKey())
+	return move, nil
+}
+
+// Takes back the last move, restoring the position exactly as it was before the move
+//
+// Returns the move that was undone
+func (g *Game) Undo() (Move, error) {
+	if len(g.MoveHistory) == 0 || len(g.undoStack) == 0 {
+		return Move{}, ErrNoMoveToUndo
+	}
+	move := g.MoveHistory[len(g.MoveHistory)-1]
+	record := g.undoStack[len(g.undoStack)-1]
+	pos := g.position()
+	pos.unmakeMove(record.move, record.undo)
+	g.legal = nil
+	g.Board.captured = g.Board.captured[:len(g.Board.captured)-record.captured]
+	pos.fillBoard(g.Board)
+	g.MoveHistory = g.MoveHistory[:len(g.MoveHistory)-1]
+	g.undoStack = g.undoStack[:len(g.undoStack)-1]
+	g.positions = g.positions[:len(g.positions)-1]
+	g.HalfmoveClock = record.halfmoveClock
+	g.FullmoveNumber = record.fullmoveNumber
+	g.changeTurn()
+	return move, nil
+}
+
+// Returns a copy of the game before its last move, where the move's variations start
+func (g *Game) beforeLastMove() (*Game, error) {
+	previous := g.Clone()
+	_, err := previous.Undo()
+	return previous, err
+}
+
+// Returns the pieces a move took off the board, including those removed by an
+// explosion in atomic
+//
+// us is the colour of the side that made the move
+func capturedPieces(move posMove, undo posUndo, us int) []*Piece {
+	captured := []*Piece{}
+	if move.flags&moveCapture != 0 {
+		captured = append(captured, &Piece{PieceType: move.captured, Color: colorName(1 - us)})
+	}
+	for color := white; color <= black; color++ {
+		for pieceType := King; pieceType <= Pawn; pieceType++ {
+			for n := bits.OnesCount64(uint64(undo.exploded[color][pieceType])); n > 0; n-- {
+				captured = append(captured, &Piece{PieceType: PieceType(pieceType), Color: colorName(color)})
+			}
 		}
-		err = b.MovePiece(Move{
-			Piece:    'R',
-			FromFile: 'h',
-			FromRank: homeRank,
-			ToFile:   'f',
-			ToRank:   homeRank,
-		})
-		if err != nil {
-			// Undo the king move
-			b.MovePiece(Move{
-				Piece:    'K',
-				FromFile: 'g',
-				FromRank: homeRank,
-				ToFile:   'e',
-				ToRank:   homeRank,
-			})
-			king.Moved = false
-			return err
+	}
+	return captured
+}
+
+// Returns the position, set to the rules of the game's variant
+//
+// The variant can be changed after the game is created, so the legal moves
+// are generated again when it has
+func (g *Game) position() *position {
+	if g.pos.variant != g.Variant {
+		g.pos.variant = g.Variant
+		g.legal = nil
+	}
+	return g.pos
+}
+
+// Returns the legal moves in the position, generating them once per position
+func (g *Game) legalMoves() []posMove {
+	pos := g.position()
+	if g.legal == nil {
+		g.legal = pos.legalMoves()
+	}
+	return g.legal
+}
+
+// Finds the legal move matching a move from the list of possible moves
+//
+// Castling may be given without the files the king and rook start on
+func (g *Game) findPosMove(move Move) (posMove, bool) {
+	for _, pm := range g.legalMoves() {
+		if pm.flags&moveCastle != 0 {
+			if move.Castle != "" && (pm.to > pm.from) == (move.Castle == "short") {
+				return pm, true
+			}
+			continue
 		}
-	} else if castletype == "long" {
-		err = b.MovePiece(Move{
-			Piece:    'K',
-			FromFile: 'e',
-			FromRank: homeRank,
-			ToFile:   'c',
-			ToRank:   homeRank,
-		})
-		if err != nil {
-			return err
+		if move.Castle != "" || pm.from != square(move.FromFile, move.FromRank) || pm.to != square(move.ToFile, move.ToRank) {
+			continue
+		}
+		var promotion rune
+		if pm.flags&movePromotion != 0 {
+			promotion, _ = (&Piece{PieceType: pm.promotion}).getSymbol()
 		}
-		err = b.MovePiece(Move{
-			Piece:    'R',
-			FromFile: 'a',
-			FromRank: homeRank,
-			ToFile:   'd',
-			ToRank:   homeRank,
-		})
+		if promotion == move.Promotion {
+			return pm, true
+		}
+	}
+	return posMove{}, false
+}
+
+// Returns the short algebraic notation of a possible move in the current position
+// Includes the file and/or rank the piece moves from if another piece of the same type
+// can move to the same square, and + or # if the move gives check or checkmate
+func (g *Game) SAN(move Move) (string, error) {
+	pm, ok := g.findPosMove(move)
+	if !ok {
+		return "", ErrInvalidMove
+	}
+	legal := g.legalMoves()
+	pos := g.position()
+	move = pos.toMove(pm)
+	undo := pos.makeMove(pm)
+	move.CheckStatus = pos.checkStatus()
+	pos.unmakeMove(pm, undo)
+	if move.Castle != "" {
+		notation, err := move.ShortAlgebraicNotation(false, false)
+		if err != nil || move.CheckStatus == 0 {
+			return notation, err
+		}
+		return notation + string(move.CheckStatus), nil
+	}
+	var ambiguous, sameFile, sameRank bool
+	if pm.piece != Pawn {
+		for _, other := range legal {
+			if other.piece != pm.piece || other.flags&moveCastle != 0 ||
+				other.to != pm.to || other.from == pm.from {
+				continue
+			}
+			ambiguous = true
+			if other.from%8 == pm.from%8 {
+				sameFile = true
+			}
+			if other.from/8 == pm.from/8 {
+				sameRank = true
+			}
+		}
+	}
+	includeFile := ambiguous && (!sameFile || sameRank)
+	includeRank := ambiguous && sameFile
+	return move.ShortAlgebraicNotation(includeFile, includeRank)
+}
+
+// Returns the square a pawn can move to when capturing en passant
+// ok is false if no en passant capture is available
+func (g *Game) enPassantSquare() (file rune, rank int, ok bool) {
+	if g.pos.epSquare == noSquare {
+		return 0, 0, false
+	}
+	file, rank = squareToFileRank(g.pos.epSquare)
+	return file, rank, true
+}
+
+// Takes a slice of move strings in algebraic notation and plays them
+func (g *Game) Moves(moveStrs []string) error {
+	for _, moveStr := range moveStrs {
+		_, err := g.Move(moveStr)
 		if err != nil {
-			// Undo the king move
-			b.MovePiece(Move{
-				Piece:    'K',
-				FromFile: 'c',
-				FromRank: homeRank,
-				ToFile:   'e',
-				ToRank:   homeRank,
-			})
-			king.Moved = false
 			return err
 		}
-	} else {
-		return ErrInvalidMove
 	}
 	return nil
 }
 
+// Get all legal moves for the current player
+func (g *Game) GetPossibleMoves() []Move {
+	pos := g.position()
+	possibleMoves := []Move{}
+	for _, move := range g.legalMoves() {
+		possibleMoves = append(possibleMoves, pos.toMove(move))
+	}
+	return possibleMoves
+}
+
+// Get the legal moves of the piece on the square, including castling for the king
+func (g *Game) MovesFrom(file rune, rank int) []Move {
+	moves := []Move{}
+	for _, move := range g.GetPossibleMoves() {
+		if move.FromFile == file && move.FromRank == rank {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+// Castles the king of the side to move
+//
+// castletype is "short" or "long"
+func (g *Game) Castle(castletype string) error {
+	if castletype != "short" && castletype != "long" {
+		return ErrInvalidMove
+	}
+	_, err := g.play(Move{Castle: castletype})
+	return err
+}
+
 func (g *Game) changeTurn() {
 	if g.Turn == "white" {
 		g.Turn = "black"
@@ -316,27 +602,49 @@ func (g *Game) NewGame() {
 	g.Board = NewBoard()
 	g.Turn = "white"
 	g.MoveHistory = []Move{}
+	g.HalfmoveClock = 0
+	g.FullmoveNumber = 1
+	g.Chess960 = false
+	g.Variant = Standard
+	g.pos = newPosition(g)
+	g.legal = nil
+	g.positions = []uint64{g.positionKey()}
+	g.undoStack = nil
 }
 
 func (g *Game) Clone() *Game {
+	moveHistory := make([]Move, len(g.MoveHistory))
+	copy(moveHistory, g.MoveHistory)
+	positions := make([]uint64, len(g.positions))
+	copy(positions, g.positions)
+	undoStack := make([]undoRecord, len(g.undoStack))
+	copy(undoStack, g.undoStack)
+	pos := *g.pos
 	return &Game{
-		Board:       g.Board.Clone(),
-		Turn:        g.Turn,
-		MoveHistory: g.MoveHistory,
+		Board:          g.Board.Clone(),
+		Turn:           g.Turn,
+		MoveHistory:    moveHistory,
+		HalfmoveClock:  g.HalfmoveClock,
+		FullmoveNumber: g.FullmoveNumber,
+		Chess960:       g.Chess960,
+		Variant:        g.Variant,
+		pos:            &pos,
+		positions:      positions,
+		undoStack:      undoStack,
 	}
 }
 
 // Parse a move string in algebraic notation
 func parseRegex(moveStr string) (Move, error) {
 	// Regex to parse move string
-	pattern := `^([NBRQK])?([a-h])?([1-8])?(x)?([a-h])([1-8])(=[NBRQK])?(\+|#)?$|^O-O(-O)?$`
+	pattern := `^([NBRQK])?([a-h])?([1-8])?(x|-)?([a-h])([1-8])(=[NBRQK])?(\+|#)?$|^O-O(-O)?$`
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		return Move{}, err
 	}
 	matches := re.FindStringSubmatch(moveStr)
 	if matches == nil {
-		return Move{}, err
+		return Move{}, ErrInvalidMove
 	}
 	move := Move{}
 	if matches[0] == "O-O" {
@@ -353,8 +661,8 @@ func parseRegex(moveStr string) (Move, error) {
 		if matches[3] != "" {
 			move.FromRank = int(matches[3][0] - '0')
 		}
-		if matches[4] != "" {
-			move.Capture = rune(matches[4][0])
+		if matches[4] == "x" {
+			move.Capture = 'x'
 		}
 		move.ToFile = rune(matches[5][0])
 		move.ToRank = int(matches[6][0] - '0')
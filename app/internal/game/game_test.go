@@ -1,7 +1,10 @@
 package game
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -68,6 +71,16 @@ func TestParseRegex(t *testing.T) {
 				Castle: "short",
 			},
 		},
+		{
+			input: "Ng1-f3",
+			expected: Move{
+				Piece:    'N',
+				FromFile: 'g',
+				FromRank: 1,
+				ToFile:   'f',
+				ToRank:   3,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -115,11 +128,11 @@ func TestPossibleMoves(t *testing.T) {
 }
 
 func TestPossibleMovesDuplicate(t *testing.T) {
-	g := NewGame()
-	b := g.Board
-	// Move rooks up to test multiple pieces of the same type moving to the same square
-	b.MovePiece(Move{FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 3})
-	b.MovePiece(Move{FromFile: 'h', FromRank: 1, ToFile: 'h', ToRank: 3})
+	// Rooks moved up to test multiple pieces of the same type moving to the same square
+	g, err := NewGameFromFEN("rnbqkbnr/pppppppp/8/8/8/R6R/PPPPPPPP/1NBQKBN1 w kq - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	moves := g.GetPossibleMoves()
 	expectedMoves := []string{
 		// Pawn moves
@@ -158,14 +171,11 @@ func TestPossibleMovesDuplicate(t *testing.T) {
 }
 
 func TestPossibleMovesCastle(t *testing.T) {
-	g := NewGame()
-	b := g.Board
-	// Move pieces up to open up castling
-	b.MovePiece(Move{FromFile: 'b', FromRank: 1, ToFile: 'b', ToRank: 3})
-	b.MovePiece(Move{FromFile: 'c', FromRank: 1, ToFile: 'c', ToRank: 3})
-	b.MovePiece(Move{FromFile: 'd', FromRank: 1, ToFile: 'd', ToRank: 3})
-	b.MovePiece(Move{FromFile: 'f', FromRank: 1, ToFile: 'f', ToRank: 3})
-	b.MovePiece(Move{FromFile: 'g', FromRank: 1, ToFile: 'g', ToRank: 3})
+	// Pieces moved up to open up castling
+	g, err := NewGameFromFEN("rnbqkbnr/pppppppp/8/8/8/1NBQ1BN1/PPPPPPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 	moves := g.GetPossibleMoves()
 	expectedMoves := []string{
 		// Pawn moves
@@ -236,6 +246,57 @@ func TestMove(t *testing.T) {
 	}
 }
 
+func TestMoveNotations(t *testing.T) {
+	tests := []struct {
+		fen      string
+		move     string
+		expected string // long algebraic notation of the move played
+		err      error
+	}{
+		{StartingFEN, "e2e4", "e2e4", nil},
+		{StartingFEN, "e2-e4", "e2e4", nil},
+		{StartingFEN, "g1f3", "Ng1f3", nil},
+		{StartingFEN, "Ng1f3", "Ng1f3", nil},
+		{StartingFEN, "Ng1-f3", "Ng1f3", nil},
+		{StartingFEN, "Bg1f3", "", ErrInvalidMove},
+		{StartingFEN, "e2e5", "", ErrInvalidMove},
+		{StartingFEN, "e7e5", "", ErrInvalidMove},
+		// Promotion
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8n", "e7xd8=N", nil},
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7e8Q", "e7e8=Q+", nil},
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7xd8=N", "e7xd8=N", nil},
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8=N", "e7xd8=N", nil},
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8", "", ErrInvalidMove},
+		// Castling as the king moving two squares or capturing its own rook
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", "O-O", nil},
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1h1", "O-O", nil},
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "Ke1g1", "O-O", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			move, err := g.Move(tt.move)
+			if err != tt.err {
+				t.Fatalf("Expected: %v, got: %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			notation, err := move.LongAlgebraicNotation()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if notation != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, notation)
+			}
+		})
+	}
+}
+
 func TestMoveAmbiguous(t *testing.T) {
 	g := NewGame()
 	tests := []struct {
@@ -363,3 +424,278 @@ func TestClone(t *testing.T) {
 		}
 	}
 }
+
+func TestPossibleMovesLegal(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		legal    []string
+		illegal  []string
+		expected int
+	}{
+		{
+			name:    "pinned knight",
+			fen:     "4k3/8/8/b7/8/8/3N4/4K1N1 w - - 0 1",
+			legal:   []string{"Nf3", "Ne2", "Kd1"},
+			illegal: []string{"Nb3", "Nb1", "Kd2"},
+		},
+		{
+			name:    "pinned bishop moves along the pin",
+			fen:     "4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1",
+			legal:   []string{"Kd2", "Kf1"},
+			illegal: []string{"Bd3", "Bf3", "Ke2"},
+		},
+		{
+			name:    "king can't walk into check",
+			fen:     "4k3/8/8/8/8/2p5/8/4K3 w - - 0 1",
+			legal:   []string{"Kd1", "Ke2", "Kf2"},
+			illegal: []string{"Kd2"},
+		},
+		{
+			name:    "must get out of check",
+			fen:     "4k3/8/8/8/4r3/8/8/R3K3 w Q - 0 1",
+			legal:   []string{"Kd1", "Kf2", "Kd2"},
+			illegal: []string{"O-O-O", "Ra8", "Ke2"},
+		},
+		{
+			name:    "castling through check",
+			fen:     "3rk2r/8/8/8/8/8/8/R3K2R w KQk - 0 1",
+			legal:   []string{"O-O"},
+			illegal: []string{"O-O-O"},
+		},
+		{
+			name:    "castling into check",
+			fen:     "4k3/8/8/8/8/8/6r1/R3K2R w KQ - 0 1",
+			legal:   []string{"O-O-O"},
+			illegal: []string{"O-O"},
+		},
+		{
+			name:  "queenside castling with the b file attacked",
+			fen:   "1r2k3/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			legal: []string{"O-O-O"},
+		},
+		{
+			name:    "en passant exposing the king",
+			fen:     "8/8/8/KPp4r/8/8/8/7k w - c6 0 1",
+			legal:   []string{"b6", "Ka4"},
+			illegal: []string{"bxc6"},
+		},
+		{
+			name:     "checkmate has no moves",
+			fen:      "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.legal == nil && len(g.GetPossibleMoves()) != tt.expected {
+				t.Errorf("Expected %d possible moves, got %d", tt.expected, len(g.GetPossibleMoves()))
+			}
+			for _, move := range tt.legal {
+				_, err := g.Clone().Move(move)
+				if err != nil {
+					t.Errorf("Expected %s to be legal, got: %v", move, err)
+				}
+			}
+			for _, move := range tt.illegal {
+				_, err := g.Clone().Move(move)
+				if err != ErrInvalidMove {
+					t.Errorf("Expected %s to be illegal, got: %v", move, err)
+				}
+			}
+		})
+	}
+	// Only the unpinned knight can move so the move isn't ambiguous
+	g, err := NewGameFromFEN("4k3/8/8/b7/8/8/3N4/4K1N1 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	move, err := g.Move("Nf3")
+	if err != nil || move.FromFile != 'g' {
+		t.Errorf("Expected Ng1-f3, got %v: %v", move, err)
+	}
+}
+
+func TestMoveOnlyLegalMove(t *testing.T) {
+	// Ke7 is the only legal move, other moves must still be rejected
+	g, err := NewGameFromFEN("r1bqkb1r/pppp1Bpp/2n2n2/4p2Q/4P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, move := range []string{"Kxf7", "Kf8", "e4", "O-O"} {
+		_, err := g.Move(move)
+		if err != ErrInvalidMove {
+			t.Errorf("Expected %s to be invalid, got: %v", move, err)
+		}
+	}
+	_, err = g.Move("Ke7")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+	}{
+		{
+			name:  "opening with castling",
+			fen:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			moves: []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6", "e1g1", "f6e4"},
+		},
+		{
+			name:  "en passant",
+			fen:   "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			moves: []string{"e5f6", "g7f6", "d1h5"},
+		},
+		{
+			name:  "promotion with capture",
+			fen:   "r3k2r/1P6/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			moves: []string{"b7a8q", "e8e7", "e1c1", "h8a8"},
+		},
+	}
+	for _, tt := range tests {
+		g, err := NewGameFromFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		fens := []string{g.FEN()}
+		squares := [][8][8]Piece{copySquares(g.Board)}
+		for _, move := range tt.moves {
+			_, err := g.MoveUCI(move)
+			if err != nil {
+				t.Fatalf("%s: move %s: %v", tt.name, move, err)
+			}
+			fens = append(fens, g.FEN())
+			squares = append(squares, copySquares(g.Board))
+		}
+		for i := len(tt.moves) - 1; i >= 0; i-- {
+			_, err := g.Undo()
+			if err != nil {
+				t.Fatalf("%s: undo %s: %v", tt.name, tt.moves[i], err)
+			}
+			if g.FEN() != fens[i] {
+				t.Errorf("%s: undo %s: expected FEN %s, got %s", tt.name, tt.moves[i], fens[i], g.FEN())
+			}
+			if !reflect.DeepEqual(copySquares(g.Board), squares[i]) {
+				t.Errorf("%s: undo %s: board does not match", tt.name, tt.moves[i])
+			}
+			if len(g.MoveHistory) != i {
+				t.Errorf("%s: undo %s: expected %d moves in history, got %d", tt.name, tt.moves[i], i, len(g.MoveHistory))
+			}
+		}
+		if len(g.Board.captured) != 0 {
+			t.Errorf("%s: expected no captured pieces, got %d", tt.name, len(g.Board.captured))
+		}
+		_, err = g.Undo()
+		if err != ErrNoMoveToUndo {
+			t.Errorf("%s: expected ErrNoMoveToUndo, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestUndoAllMoves(t *testing.T) {
+	g, err := NewGameFromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fen := g.FEN()
+	squares := copySquares(g.Board)
+	for _, move := range g.GetPossibleMoves() {
+		_, err := g.PlayMove(move)
+		if err != nil {
+			t.Fatalf("%+v: %v", move, err)
+		}
+		_, err = g.Undo()
+		if err != nil {
+			t.Fatalf("%+v: %v", move, err)
+		}
+		if g.FEN() != fen || !reflect.DeepEqual(copySquares(g.Board), squares) {
+			t.Fatalf("%+v: position not restored, got %s", move, g.FEN())
+		}
+	}
+}
+
+// Copy the pieces on the board by value so later moves can't change them
+func copySquares(b *Board) [8][8]Piece {
+	var squares [8][8]Piece
+	for rank := range b.Squares {
+		for file, piece := range b.Squares[rank] {
+			if piece != nil {
+				squares[rank][file] = *piece
+			}
+		}
+	}
+	return squares
+}
+
+func TestUCILineToSAN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		line     []string
+		expected []string
+		err      error
+	}{
+		{StartingFEN, []string{"g1f3", "b8c6", "e2e4"}, []string{"1. Nf3", "Nc6", "2. e4"}, nil},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", []string{"e7e5", "g1f3"}, []string{"1... e5", "2. Nf3"}, nil},
+		// Disambiguation and check
+		{"4k3/8/8/8/8/8/8/RN2KN2 w - - 0 30", []string{"f1d2", "e8d7", "a1a7"}, []string{"30. Nfd2", "Kd7", "31. Ra7+"}, nil},
+		// Cut short at a move that can't be played
+		{StartingFEN, []string{"e2e4", "e2e4"}, []string{"1. e4"}, ErrInvalidMove},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			line, err := g.UCILineToSAN(tt.line)
+			if err != tt.err {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(line, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, line)
+			}
+			if g.FEN() != tt.fen {
+				t.Errorf("Expected the game to be left at %s, got %s", tt.fen, g.FEN())
+			}
+		})
+	}
+}
+
+func TestMovesFrom(t *testing.T) {
+	g, err := NewGameFromFEN("r3k2r/8/8/8/8/8/3P4/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		file         rune
+		rank         int
+		destinations []string
+	}{
+		{'e', 1, []string{"c1", "d1", "e2", "f1", "f2", "g1"}},
+		{'d', 2, []string{"d3", "d4"}},
+		{'a', 8, []string{}}, // not the side to move
+		{'b', 4, []string{}}, // empty square
+	}
+
+	for _, tt := range tests {
+		destinations := []string{}
+		for _, move := range g.MovesFrom(tt.file, tt.rank) {
+			file, rank := move.Destination()
+			destinations = append(destinations, fmt.Sprintf("%c%d", file, rank))
+		}
+		sort.Strings(destinations)
+		if strings.Join(destinations, " ") != strings.Join(tt.destinations, " ") {
+			t.Errorf("Expected destinations %v from %c%d, got %v", tt.destinations, tt.file, tt.rank, destinations)
+		}
+	}
+}
@@ -23,6 +23,18 @@ type Move struct {
 	Castle      string
 }
 
+// Returns the square the moving piece ends on
+//
+// Castling moves are stored as the king capturing its own rook,
+// so the king's destination is returned instead
+func (m Move) Destination() (file rune, rank int) {
+	if m.Castle == "" {
+		return m.ToFile, m.ToRank
+	}
+	kingFile, _ := castlingDestinations(m.Castle)
+	return kingFile, m.FromRank
+}
+
 // Returns the long algebraic notation of the move
 func (m Move) LongAlgebraicNotation() (string, error) {
 	var piece, capture, promotion, checkStatus string
@@ -112,11 +124,17 @@ func (m Move) ShortAlgebraicNotation(includeFile, includeRank bool) (string, err
 }
 
 // Returns the UCI notation of the move
+//
+// Castling is written as the king capturing its own rook, as engines expect
+// with UCI_Chess960, when the move gives the files of the king and rook
 func (m Move) UCInotation() (string, error) {
 	var promotion string
 	if m.FromRank == 0 {
 		return "", ErrNotEnoughInfo
 	}
+	if m.Castle != "" && m.FromFile != 0 && m.ToFile != 0 {
+		return fmt.Sprintf("%c%d%c%d", m.FromFile, m.FromRank, m.ToFile, m.FromRank), nil
+	}
 	if m.Castle == "short" {
 		return fmt.Sprintf("e%dg%d", m.FromRank, m.FromRank), nil
 	} else if m.Castle == "long" {
@@ -206,12 +224,14 @@ func getCorrespondingMove(move Move, possibleMoves []Move) (Move, error) {
 	// Create a new slice to hold the filtered moves
 	for _, filterType := range []string{"castle", "mandatory", "file", "rank", "promotion", "check"} {
 		possibleMoves = filterMoves(move, possibleMoves, filterType)
-		if len(possibleMoves) == 1 {
-			return possibleMoves[0], nil
-		}
 		if len(possibleMoves) == 0 {
 			return Move{}, ErrInvalidMove
 		}
+		// The piece and destination must always be checked,
+		// even if only one move is possible, unless castling
+		if len(possibleMoves) == 1 && (filterType != "castle" || move.Castle != "") {
+			return possibleMoves[0], nil
+		}
 	}
 	return Move{}, ErrAmbiguousMove
 }
@@ -563,3 +563,24 @@ func TestGetCorrespondingMove(t *testing.T) {
 		}
 	}
 }
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		move Move
+		file rune
+		rank int
+	}{
+		{Move{Piece: 'N', FromFile: 'g', FromRank: 1, ToFile: 'f', ToRank: 3}, 'f', 3},
+		{Move{FromFile: 'e', FromRank: 1, ToFile: 'h', ToRank: 1, Castle: "short"}, 'g', 1},
+		{Move{FromFile: 'e', FromRank: 8, ToFile: 'a', ToRank: 8, Castle: "long"}, 'c', 8},
+		// Chess960 king on b1 castling long with the rook on a1
+		{Move{FromFile: 'b', FromRank: 1, ToFile: 'a', ToRank: 1, Castle: "long"}, 'c', 1},
+	}
+
+	for _, tt := range tests {
+		file, rank := tt.move.Destination()
+		if file != tt.file || rank != tt.rank {
+			t.Errorf("Expected destination %c%d, got %c%d", tt.file, tt.rank, file, rank)
+		}
+	}
+}
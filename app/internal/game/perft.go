@@ -0,0 +1,30 @@
+package game
+
+// Counts the leaf nodes of the move tree to the given depth
+//
+// Used to check move generation against known results
+func (g *Game) Perft(depth int) int {
+	pos := *g.position()
+	return pos.perft(depth)
+}
+
+// Counts the leaf nodes of the move tree to the given depth for each possible move
+//
+// Returns a map of moves in UCI notation to the number of nodes after the move
+func (g *Game) Divide(depth int) map[string]int {
+	divide := map[string]int{}
+	if depth < 1 {
+		return divide
+	}
+	pos := *g.position()
+	for _, move := range pos.legalMoves() {
+		uci, err := pos.toMove(move).UCInotation()
+		if err != nil {
+			continue
+		}
+		undo := pos.makeMove(move)
+		divide[uci] = pos.perft(depth - 1)
+		pos.unmakeMove(move, undo)
+	}
+	return divide
+}
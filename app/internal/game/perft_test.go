@@ -0,0 +1,139 @@
+package game
+
+import "testing"
+
+// Positions and node counts from https://www.chessprogramming.org/Perft_Results
+var perftTests = []struct {
+	name  string
+	fen   string
+	nodes []int // nodes at depth 1, 2, ...
+}{
+	{
+		name:  "initial",
+		fen:   StartingFEN,
+		nodes: []int{20, 400, 8902, 197281, 4865609},
+	},
+	{
+		name:  "kiwipete",
+		fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		nodes: []int{48, 2039, 97862, 4085603},
+	},
+	{
+		name:  "position 3",
+		fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		nodes: []int{14, 191, 2812, 43238, 674624},
+	},
+	{
+		name:  "position 4",
+		fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		nodes: []int{6, 264, 9467, 422333},
+	},
+	{
+		name:  "position 4 mirrored",
+		fen:   "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+		nodes: []int{6, 264, 9467, 422333},
+	},
+	{
+		name:  "position 5",
+		fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		nodes: []int{44, 1486, 62379, 2103487},
+	},
+	{
+		name:  "position 6",
+		fen:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		nodes: []int{46, 2079, 89890, 3894594},
+	},
+	// Chess960 positions from https://www.chessprogramming.org/Chess960_Perft_Results
+	{
+		name:  "chess960 position 1",
+		fen:   "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		nodes: []int{21, 528, 12189, 326672},
+	},
+	{
+		name:  "chess960 position 2",
+		fen:   "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+		nodes: []int{21, 807, 18002, 667366},
+	},
+	{
+		name:  "chess960 position 3",
+		fen:   "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9",
+		nodes: []int{20, 479, 10471, 273318},
+	},
+	{
+		name:  "chess960 position 4",
+		fen:   "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+		nodes: []int{22, 593, 13440, 382958},
+	},
+	{
+		name:  "chess960 position 5",
+		fen:   "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9",
+		nodes: []int{28, 1120, 31058, 1171749},
+	},
+	{
+		name:  "chess960 position 6",
+		fen:   "qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9",
+		nodes: []int{29, 899, 26578, 824055},
+	},
+	{
+		name:  "chess960 position 7",
+		fen:   "q1bnrkr1/ppppp2p/2n2p2/4b1p1/2NP4/8/PPP1PPPP/QNB1RRKB w ge - 1 9",
+		nodes: []int{30, 860, 24566, 732757},
+	},
+}
+
+// Deeper searches take too long for go test -short
+const perftShortNodes = 100000
+
+func TestPerft(t *testing.T) {
+	for _, tt := range perftTests {
+		for i, expected := range tt.nodes {
+			depth := i + 1
+			if testing.Short() && expected > perftShortNodes {
+				break
+			}
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			actual := g.Perft(depth)
+			if actual != expected {
+				t.Errorf("%s depth %d: expected %d nodes, got %d", tt.name, depth, expected, actual)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	g := NewGame()
+	divide := g.Divide(2)
+	if len(divide) != 20 {
+		t.Errorf("Expected 20 moves, got %d", len(divide))
+	}
+	total := 0
+	for move, nodes := range divide {
+		if nodes != 20 {
+			t.Errorf("Expected 20 nodes after %s, got %d", move, nodes)
+		}
+		total += nodes
+	}
+	if total != 400 {
+		t.Errorf("Expected 400 nodes, got %d", total)
+	}
+	// Castling, en passant and promotions are listed by their UCI notation
+	g, err := NewGameFromFEN("r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]int{
+		"c4c5": 1, "d2d4": 1, "f1f2": 1, "f3d4": 1, "b4c5": 1, "g1h1": 1,
+	}
+	divide = g.Divide(1)
+	if len(divide) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, divide)
+	}
+	for move := range expected {
+		if divide[move] != 1 {
+			t.Errorf("Expected %s in %v", move, divide)
+		}
+	}
+}
@@ -0,0 +1,528 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidPGN = errors.New("invalid PGN")
+
+// The tags every PGN game is expected to have, in export order
+var SevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// A game read from PGN
+type PGNGame struct {
+	Tags   map[string]string
+	Moves  []*PGNMove // mainline
+	Result string     // 1-0, 0-1, 1/2-1/2 or *
+	Game   *Game      // state after playing the mainline
+}
+
+// A move in the movetext of a PGN game
+type PGNMove struct {
+	Move       Move
+	SAN        string       // notation the move was written in
+	PreComment string       // comment before the first move of a line
+	Comments   []string     // comments following the move
+	NAGs       []int        // numeric annotation glyphs e.g. $1 for !
+	Variations [][]*PGNMove // alternatives to this move
+}
+
+type pgnTokenType int
+
+const (
+	tagToken pgnTokenType = iota
+	moveNumberToken
+	sanToken
+	nagToken
+	commentToken
+	openVariationToken
+	closeVariationToken
+	resultToken
+)
+
+type pgnToken struct {
+	tokenType pgnTokenType
+	value     string
+	tagValue  string
+}
+
+// Suffix annotations and their equivalent NAGs
+var suffixAnnotations = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// Reads all games from a PGN string
+//
+// Games that start from a custom position must include a FEN tag
+func ReadPGN(pgn string) ([]*PGNGame, error) {
+	tokens, err := tokenizePGN(pgn)
+	if err != nil {
+		return nil, err
+	}
+	games := []*PGNGame{}
+	p := &pgnParser{tokens: tokens}
+	for p.pos < len(p.tokens) {
+		pgnGame, err := p.parseGame()
+		if err != nil {
+			return nil, fmt.Errorf("%w: game %d: %v", ErrInvalidPGN, len(games)+1, err)
+		}
+		games = append(games, pgnGame)
+	}
+	return games, nil
+}
+
+// Splits a PGN string into tokens
+func tokenizePGN(pgn string) ([]pgnToken, error) {
+	tokens := []pgnToken{}
+	runes := []rune(pgn)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '%' && (i == 0 || runes[i-1] == '\n'):
+			// Escaped line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == ';':
+			// Rest of line comment
+			start := i + 1
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, pgnToken{tokenType: commentToken, value: strings.TrimSpace(string(runes[start:i]))})
+		case r == '{':
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated comment", ErrInvalidPGN)
+			}
+			comment := strings.Join(strings.Fields(string(runes[i+1:end])), " ")
+			tokens = append(tokens, pgnToken{tokenType: commentToken, value: comment})
+			i = end + 1
+		case r == '[':
+			token, end, err := tokenizeTag(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+		case r == '(':
+			tokens = append(tokens, pgnToken{tokenType: openVariationToken, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, pgnToken{tokenType: closeVariationToken, value: ")"})
+			i++
+		case r == '$':
+			end := i + 1
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("%w: invalid NAG", ErrInvalidPGN)
+			}
+			tokens = append(tokens, pgnToken{tokenType: nagToken, value: string(runes[i+1 : end])})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("{}[]();$", runes[end]) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidPGN, r)
+			}
+			tokens = append(tokens, tokenizeSymbol(string(runes[i:end]))...)
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// Reads a tag pair such as [Event "F/S Return Match"] starting at runes[start]
+// Returns the token and the index after the closing bracket
+func tokenizeTag(runes []rune, start int) (pgnToken, int, error) {
+	i := start + 1
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	nameStart := i
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+		i++
+	}
+	name := string(runes[nameStart:i])
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	if name == "" || i == len(runes) || runes[i] != '"' {
+		return pgnToken{}, 0, fmt.Errorf("%w: malformed tag", ErrInvalidPGN)
+	}
+	i++
+	var value strings.Builder
+	for i < len(runes) && runes[i] != '"' {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+		}
+		value.WriteRune(runes[i])
+		i++
+	}
+	if i == len(runes) {
+		return pgnToken{}, 0, fmt.Errorf("%w: malformed tag", ErrInvalidPGN)
+	}
+	i++
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	if i == len(runes) || runes[i] != ']' {
+		return pgnToken{}, 0, fmt.Errorf("%w: malformed tag", ErrInvalidPGN)
+	}
+	return pgnToken{tokenType: tagToken, value: name, tagValue: value.String()}, i + 1, nil
+}
+
+// Splits a symbol into move number, SAN, suffix annotation and result tokens
+//
+// e.g. "12.Nf3!?" -> "12." "Nf3" "$5"
+func tokenizeSymbol(symbol string) []pgnToken {
+	switch symbol {
+	case "1-0", "0-1", "1/2-1/2", "*":
+		return []pgnToken{{tokenType: resultToken, value: symbol}}
+	}
+	tokens := []pgnToken{}
+	// Move number indication
+	digits := 0
+	for digits < len(symbol) && unicode.IsDigit(rune(symbol[digits])) {
+		digits++
+	}
+	if digits > 0 && digits < len(symbol) && symbol[digits] == '.' {
+		end := digits
+		for end < len(symbol) && symbol[end] == '.' {
+			end++
+		}
+		tokens = append(tokens, pgnToken{tokenType: moveNumberToken, value: symbol[:end]})
+		symbol = symbol[end:]
+	} else if digits == len(symbol) {
+		return []pgnToken{{tokenType: moveNumberToken, value: symbol}}
+	}
+	if symbol == "" {
+		return tokens
+	}
+	// Suffix annotation
+	san := strings.TrimRight(symbol, "!?")
+	suffix := symbol[len(san):]
+	tokens = append(tokens, pgnToken{tokenType: sanToken, value: san})
+	if nag, ok := suffixAnnotations[suffix]; ok {
+		tokens = append(tokens, pgnToken{tokenType: nagToken, value: strconv.Itoa(nag)})
+	}
+	return tokens
+}
+
+type pgnParser struct {
+	tokens []pgnToken
+	pos    int
+}
+
+// Parses the tag pairs and movetext of a single game
+func (p *pgnParser) parseGame() (*PGNGame, error) {
+	pgnGame := &PGNGame{
+		Tags:   map[string]string{},
+		Moves:  []*PGNMove{},
+		Result: "*",
+	}
+	for p.pos < len(p.tokens) && p.tokens[p.pos].tokenType == tagToken {
+		pgnGame.Tags[p.tokens[p.pos].value] = p.tokens[p.pos].tagValue
+		p.pos++
+	}
+	g, err := startingPosition(pgnGame.Tags)
+	if err != nil {
+		return nil, err
+	}
+	moves, err := p.parseLine(g, true)
+	if err != nil {
+		return nil, err
+	}
+	pgnGame.Moves = moves
+	pgnGame.Game = g
+	if p.pos < len(p.tokens) && p.tokens[p.pos].tokenType == resultToken {
+		pgnGame.Result = p.tokens[p.pos].value
+		p.pos++
+	} else if result, ok := pgnGame.Tags["Result"]; ok {
+		pgnGame.Result = result
+	}
+	return pgnGame, nil
+}
+
+// Parses a line of moves, playing them on the provided game
+//
+// The mainline ends at a result or the tags of the next game,
+// variations end at their closing parenthesis
+func (p *pgnParser) parseLine(g *Game, mainline bool) ([]*PGNMove, error) {
+	moves := []*PGNMove{}
+	preComments := []string{}
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		switch token.tokenType {
+		case tagToken, resultToken:
+			if !mainline {
+				return nil, errors.New("unterminated variation")
+			}
+			return moves, nil
+		case closeVariationToken:
+			if mainline {
+				return nil, errors.New("unexpected ')'")
+			}
+			p.pos++
+			return moves, nil
+		case moveNumberToken:
+			p.pos++
+		case commentToken:
+			if len(moves) == 0 {
+				preComments = append(preComments, token.value)
+			} else {
+				last := moves[len(moves)-1]
+				last.Comments = append(last.Comments, token.value)
+			}
+			p.pos++
+		case nagToken:
+			if len(moves) == 0 {
+				return nil, errors.New("NAG before first move")
+			}
+			nag, err := strconv.Atoi(token.value)
+			if err != nil {
+				return nil, err
+			}
+			last := moves[len(moves)-1]
+			last.NAGs = append(last.NAGs, nag)
+			p.pos++
+		case openVariationToken:
+			if len(moves) == 0 {
+				return nil, errors.New("variation before first move")
+			}
+			p.pos++
+			// the game is only copied where a variation starts, so long games import quickly
+			start, err := g.beforeLastMove()
+			if err != nil {
+				return nil, err
+			}
+			variation, err := p.parseLine(start, false)
+			if err != nil {
+				return nil, err
+			}
+			last := moves[len(moves)-1]
+			last.Variations = append(last.Variations, variation)
+		case sanToken:
+			move, err := g.Move(normalizeSAN(token.value))
+			if err != nil {
+				return nil, fmt.Errorf("move %s: %v", token.value, err)
+			}
+			pgnMove := &PGNMove{
+				Move: move,
+				SAN:  token.value,
+			}
+			if len(moves) == 0 && len(preComments) > 0 {
+				pgnMove.PreComment = strings.Join(preComments, " ")
+			}
+			moves = append(moves, pgnMove)
+			p.pos++
+		}
+	}
+	if !mainline {
+		return nil, errors.New("unterminated variation")
+	}
+	return moves, nil
+}
+
+// Returns the game in PGN export format
+//
+// The seven tag roster is written first with "?" for missing values,
+// followed by the remaining tags in alphabetical order
+func (pg *PGNGame) PGN() (string, error) {
+	g, err := startingPosition(pg.Tags)
+	if err != nil {
+		return "", err
+	}
+	result := pg.Result
+	if result == "" {
+		result = "*"
+	}
+	var sb strings.Builder
+	for _, name := range SevenTagRoster {
+		value, ok := pg.Tags[name]
+		if name == "Result" {
+			value, ok = result, true
+		}
+		if !ok || value == "" {
+			value = "?"
+		}
+		writeTag(&sb, name, value)
+	}
+	others := []string{}
+	for name := range pg.Tags {
+		if !isSevenTagRoster(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		writeTag(&sb, name, pg.Tags[name])
+	}
+	sb.WriteString("\n")
+	tokens, err := movetextTokens(g, pg.Moves)
+	if err != nil {
+		return "", err
+	}
+	tokens = append(tokens, result)
+	sb.WriteString(wrapTokens(tokens, 80))
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+// Returns the position a game starts from, set by its FEN and Variant tags
+func startingPosition(tags map[string]string) (*Game, error) {
+	g := NewGame()
+	if fen, ok := tags["FEN"]; ok {
+		var err error
+		g, err = NewGameFromFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if isChess960Variant(tags["Variant"]) {
+		g.Chess960 = true
+	}
+	if variant, ok := tags["Variant"]; ok {
+		var err error
+		g.Variant, err = ParseVariant(variant)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Returns true if the Variant tag names Chess960
+func isChess960Variant(variant string) bool {
+	switch strings.ToLower(variant) {
+	case "chess960", "chess 960", "fischerandom", "fischer random", "960":
+		return true
+	}
+	return false
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, tag := range SevenTagRoster {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes a tag pair, escaping quotes and backslashes in the value
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+// Returns the movetext tokens of a line of moves played from the provided game
+//
+// Moves are written in SAN generated from the position, so the SAN they
+// were read in doesn't need to be preserved
+func movetextTokens(g *Game, moves []*PGNMove) ([]string, error) {
+	tokens := []string{}
+	needsNumber := true
+	for i, pgnMove := range moves {
+		if i == 0 && pgnMove.PreComment != "" {
+			tokens = append(tokens, "{"+pgnMove.PreComment+"}")
+		}
+		turn, fullmoveNumber := g.Turn, g.FullmoveNumber
+		san, err := g.SAN(pgnMove.Move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		_, err = g.PlayMove(pgnMove.Move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		if turn == "white" {
+			tokens = append(tokens, fmt.Sprintf("%d.", fullmoveNumber))
+		} else if needsNumber {
+			tokens = append(tokens, fmt.Sprintf("%d...", fullmoveNumber))
+		}
+		tokens = append(tokens, san)
+		needsNumber = false
+		for _, nag := range pgnMove.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		for _, comment := range pgnMove.Comments {
+			tokens = append(tokens, "{"+comment+"}")
+			needsNumber = true
+		}
+		for _, variation := range pgnMove.Variations {
+			if len(variation) == 0 {
+				continue
+			}
+			start, err := g.beforeLastMove()
+			if err != nil {
+				return nil, err
+			}
+			variationTokens, err := movetextTokens(start, variation)
+			if err != nil {
+				return nil, err
+			}
+			variationTokens[0] = "(" + variationTokens[0]
+			variationTokens[len(variationTokens)-1] += ")"
+			tokens = append(tokens, variationTokens...)
+			needsNumber = true
+		}
+	}
+	return tokens, nil
+}
+
+// Joins tokens with spaces, breaking lines before they exceed the width
+//
+// Tokens containing spaces, such as comments, may be broken between words
+func wrapTokens(tokens []string, width int) string {
+	var sb strings.Builder
+	lineLength := 0
+	for _, token := range tokens {
+		for _, word := range strings.Fields(token) {
+			if lineLength > 0 && lineLength+1+len(word) > width {
+				sb.WriteString("\n")
+				lineLength = 0
+			} else if lineLength > 0 {
+				sb.WriteString(" ")
+				lineLength++
+			}
+			sb.WriteString(word)
+			lineLength += len(word)
+		}
+	}
+	return sb.String()
+}
+
+var promotionWithoutEquals = regexp.MustCompile(`([a-h][18])([NBRQ])([+#]?)$`)
+
+// Converts the SAN variations found in the wild to the form accepted by Game.Move
+//
+// e.g. "0-0" -> "O-O", "e8Q" -> "e8=Q", "exd6e.p." -> "exd6"
+func normalizeSAN(san string) string {
+	san = strings.TrimSuffix(san, "e.p.")
+	san = strings.ReplaceAll(san, "0", "O")
+	san = promotionWithoutEquals.ReplaceAllString(san, "$1=$2$3")
+	return san
+}
@@ -0,0 +1,308 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Date "1992.11.04"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+[Annotator "Someone \"Quoted\""]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6
+4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7
+11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5
+Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2 Nxd6 21. Nc4 Nxc4 22. Bxc4 Nb6
+23. Ne5 Rae8 24. Bxf7+ Rxf7 25. Nxf7 Rxe1+ 26. Qxe1 Kxf7 27. Qe3 Qg5 28. Qxg5
+hxg5 29. b3 Ke6 30. a3 Kd6 31. axb4 cxb4 32. Ra5 Nd5 33. f3 Bc8 34. Kf2 Bf5
+35. Ra7 g6 36. Ra6+ Kc5 37. Ke1 Nf4 38. g3 Nxh3 39. Kd2 Kb5 40. Rd6 Kc5 41. Ra6
+Nf2 42. g4 Bd3 43. Re6 1/2-1/2
+
+[Event "Annotated"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
+
+{Starting comment} 1. e4 $1 e5 (1... c5 {Sicilian} 2. Nf3 (2. c3 d5) 2... d6) (1... e6) 2. Qh5?! Nc6
+; rest of line comment
+% escaped line
+3. Bc4 Nf6?? 4. Qxf7# 1-0
+
+[Event "Puzzle"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+[Result "*"]
+
+1. e4 Kd7 2. e5 Ke6 *
+`
+
+func TestReadPGN(t *testing.T) {
+	games, err := ReadPGN(testPGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(games) != 3 {
+		t.Fatalf("Expected 3 games, got %d", len(games))
+	}
+
+	// Tags and mainline
+	fischer := games[0]
+	expectedTags := map[string]string{
+		"Event":     "F/S Return Match",
+		"Site":      "Belgrade, Serbia JUG",
+		"Date":      "1992.11.04",
+		"Round":     "29",
+		"White":     "Fischer, Robert J.",
+		"Black":     "Spassky, Boris V.",
+		"Result":    "1/2-1/2",
+		"Annotator": `Someone "Quoted"`,
+	}
+	if !reflect.DeepEqual(fischer.Tags, expectedTags) {
+		t.Errorf("Expected tags %v, got %v", expectedTags, fischer.Tags)
+	}
+	if len(fischer.Moves) != 85 {
+		t.Errorf("Expected 85 moves, got %d", len(fischer.Moves))
+	}
+	if fischer.Result != "1/2-1/2" {
+		t.Errorf("Expected result 1/2-1/2, got %s", fischer.Result)
+	}
+	if len(fischer.Game.MoveHistory) != 85 {
+		t.Errorf("Expected 85 moves in game, got %d", len(fischer.Game.MoveHistory))
+	}
+	expectedComment := []string{"This opening is called the Ruy Lopez."}
+	if !reflect.DeepEqual(fischer.Moves[4].Comments, expectedComment) {
+		t.Errorf("Expected comments %v, got %v", expectedComment, fischer.Moves[4].Comments)
+	}
+	expectedFEN := "8/8/4R1p1/2k3p1/1p4P1/1P1b1P2/3K1n2/8 b - - 2 43"
+	if fischer.Game.FEN() != expectedFEN {
+		t.Errorf("Expected FEN %s, got %s", expectedFEN, fischer.Game.FEN())
+	}
+
+	// Comments, NAGs and variations
+	annotated := games[1]
+	if annotated.Result != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", annotated.Result)
+	}
+	if len(annotated.Moves) != 7 {
+		t.Fatalf("Expected 7 moves, got %d", len(annotated.Moves))
+	}
+	if annotated.Moves[0].PreComment != "Starting comment" {
+		t.Errorf("Expected starting comment, got %q", annotated.Moves[0].PreComment)
+	}
+	nags := [][]int{{1}, nil, {6}, nil, nil, {4}, nil}
+	for i, move := range annotated.Moves {
+		if !reflect.DeepEqual(move.NAGs, nags[i]) {
+			t.Errorf("Expected NAGs %v on move %d, got %v", nags[i], i, move.NAGs)
+		}
+	}
+	variations := annotated.Moves[1].Variations
+	if len(variations) != 2 {
+		t.Fatalf("Expected 2 variations, got %d", len(variations))
+	}
+	sicilian := variations[0]
+	if len(sicilian) != 3 || sicilian[0].SAN != "c5" || sicilian[2].SAN != "d6" {
+		t.Errorf("Unexpected variation %v", sicilian)
+	}
+	if !reflect.DeepEqual(sicilian[0].Comments, []string{"Sicilian"}) {
+		t.Errorf("Expected comment on variation, got %v", sicilian[0].Comments)
+	}
+	if len(sicilian[1].Variations) != 1 || len(sicilian[1].Variations[0]) != 2 {
+		t.Errorf("Expected nested variation, got %v", sicilian[1].Variations)
+	}
+	expectedMove := Move{FromFile: 'c', FromRank: 7, ToFile: 'c', ToRank: 5}
+	if sicilian[0].Move != expectedMove {
+		t.Errorf("Expected move %v, got %v", expectedMove, sicilian[0].Move)
+	}
+	if !reflect.DeepEqual(annotated.Moves[3].Comments, []string{"rest of line comment"}) {
+		t.Errorf("Expected rest of line comment, got %v", annotated.Moves[3].Comments)
+	}
+	// Variations don't affect the mainline
+	if len(annotated.Game.MoveHistory) != 7 || annotated.Game.MoveHistory[1] != (Move{FromFile: 'e', FromRank: 7, ToFile: 'e', ToRank: 5}) {
+		t.Errorf("Unexpected mainline %v", annotated.Game.MoveHistory)
+	}
+
+	// Custom starting position
+	puzzle := games[2]
+	expectedFEN = "8/8/4k3/4P3/8/8/8/4K3 w - - 1 3"
+	if puzzle.Game.FEN() != expectedFEN {
+		t.Errorf("Expected FEN %s, got %s", expectedFEN, puzzle.Game.FEN())
+	}
+	if puzzle.Result != "*" {
+		t.Errorf("Expected result *, got %s", puzzle.Result)
+	}
+}
+
+func TestReadPGNInvalid(t *testing.T) {
+	tests := []string{
+		"1. e4 e5 2. Ke3 *",
+		"1. e4 (1. d4 *",
+		"1. e4 e5) *",
+		"( 1. e4 ) *",
+		"[Event \"Unterminated] 1. e4 *",
+		"1. e4 {unterminated comment *",
+		"$1 1. e4 *",
+		"[FEN \"not a fen\"] 1. e4 *",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			_, err := ReadPGN(tt)
+			if !errors.Is(err, ErrInvalidPGN) {
+				t.Errorf("Expected error: %v, got: %v", ErrInvalidPGN, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeSAN(t *testing.T) {
+	tests := []struct {
+		san      string
+		expected string
+	}{
+		{"e4", "e4"},
+		{"0-0", "O-O"},
+		{"0-0-0+", "O-O-O+"},
+		{"e8Q", "e8=Q"},
+		{"bxa1N+", "bxa1=N+"},
+		{"e8=Q#", "e8=Q#"},
+		{"exd6e.p.", "exd6"},
+	}
+
+	for _, tt := range tests {
+		actual := normalizeSAN(tt.san)
+		if actual != tt.expected {
+			t.Errorf("Expected: %s, got: %s", tt.expected, actual)
+		}
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+	games, err := ReadPGN(testPGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, pgnGame := range games {
+		pgn, err := pgnGame.PGN()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, line := range strings.Split(pgn, "\n") {
+			if len(line) > 80 {
+				t.Errorf("Line exceeds 80 characters: %s", line)
+			}
+		}
+		reread, err := ReadPGN(pgn)
+		if err != nil {
+			t.Fatalf("Unexpected error reading exported PGN: %v\n%s", err, pgn)
+		}
+		if len(reread) != 1 {
+			t.Fatalf("Expected 1 game, got %d", len(reread))
+		}
+		if reread[0].Game.FEN() != pgnGame.Game.FEN() {
+			t.Errorf("Expected FEN %s, got %s", pgnGame.Game.FEN(), reread[0].Game.FEN())
+		}
+		if reread[0].Result != pgnGame.Result {
+			t.Errorf("Expected result %s, got %s", pgnGame.Result, reread[0].Result)
+		}
+		again, err := reread[0].PGN()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if again != pgn {
+			t.Errorf("Expected stable export:\n%s\ngot:\n%s", pgn, again)
+		}
+	}
+}
+
+func TestPGNExport(t *testing.T) {
+	games, err := ReadPGN(testPGN)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pgn, err := games[1].PGN()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[Event "Annotated"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
+
+{Starting comment} 1. e4 $1 e5 (1... c5 {Sicilian} 2. Nf3 (2. c3 d5) 2... d6)
+(1... e6) 2. Qh5 $6 Nc6 {rest of line comment} 3. Bc4 Nf6 $4 4. Qxf7# 1-0
+`
+	if pgn != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, pgn)
+	}
+	// Tags outside the seven tag roster follow it, and quotes are escaped
+	pgn, err = games[0].PGN()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(pgn, "[Result \"1/2-1/2\"]\n[Annotator \"Someone \\\"Quoted\\\"\"]\n") {
+		t.Errorf("Unexpected tags in:\n%s", pgn)
+	}
+}
+
+func TestSAN(t *testing.T) {
+	g, err := NewGameFromFEN("4k3/8/8/8/1N3N2/8/8/R3K2R w KQ - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		move     Move
+		expected string
+	}{
+		{Move{Piece: 'N', FromFile: 'b', FromRank: 4, ToFile: 'd', ToRank: 5}, "Nbd5"},
+		{Move{Piece: 'N', FromFile: 'f', FromRank: 4, ToFile: 'd', ToRank: 3}, "Nfd3"},
+		{Move{Piece: 'N', FromFile: 'f', FromRank: 4, ToFile: 'h', ToRank: 5}, "Nh5"},
+		{Move{Piece: 'R', FromFile: 'a', FromRank: 1, ToFile: 'd', ToRank: 1}, "Rd1"},
+		{Move{Piece: 'R', FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 8, CheckStatus: '+'}, "Ra8+"},
+		{Move{FromRank: 1, Castle: "short"}, "O-O"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			actual, err := g.SAN(tt.move)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Expected: %s, got: %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMoveUCI(t *testing.T) {
+	g := NewGame()
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6", "e1g1"} {
+		_, err := g.MoveUCI(uci)
+		if err != nil {
+			t.Fatalf("Unexpected error on %s: %v", uci, err)
+		}
+	}
+	expected := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 4"
+	if g.FEN() != expected {
+		t.Errorf("Expected: %s, got: %s", expected, g.FEN())
+	}
+	for _, uci := range []string{"e7e4", "a1a2", "e2", "e5e4q"} {
+		_, err := g.MoveUCI(uci)
+		if err != ErrInvalidMove {
+			t.Errorf("Expected: %v, got: %v", ErrInvalidMove, err)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package game
+
+import "math/bits"
+
+// A set of squares with one bit per square, a1 is the least significant bit and h8 the most
+type bitboard uint64
+
+// Colour indexes used by position
+const (
+	white = 0
+	black = 1
+)
+
+// Castling rights used by position
+const (
+	whiteKingside uint8 = 1 << iota
+	whiteQueenside
+	blackKingside
+	blackQueenside
+)
+
+// Value of position.epSquare when no en passant capture is possible
+const noSquare = -1
+
+// Flags describing a posMove
+const (
+	moveCapture uint8 = 1 << iota
+	moveEnPassant
+	moveCastle
+	movePromotion
+	moveDoublePush
+)
+
+// A position stored as bitboards for fast move generation
+//
+// Squares are numbered 0 (a1) to 63 (h8)
+type position struct {
+	pieces        [2][6]bitboard // indexed by colour and piece type
+	occupied      [2]bitboard    // indexed by colour
+	turn          int
+	castling      uint8
+	castlingRooks [4]int    // square of the rook for each castling right
+	castlingMasks [64]uint8 // castling rights lost when a piece moves from or to each square
+	epSquare      int
+	variant       Variant
+	checks        [2]int // checks given by each colour, counted in three-check
+}
+
+// A move in a position
+//
+// Castling is stored as the king moving to the square of the rook it castles with
+type posMove struct {
+	from, to  int
+	piece     PieceType
+	captured  PieceType // only set if the move is a capture
+	promotion PieceType // only set if the move is a promotion
+	flags     uint8
+}
+
+// The state that can't be recovered from a move when unmaking it
+type posUndo struct {
+	castling uint8
+	epSquare int
+	checks   [2]int
+	exploded [2][6]bitboard // pieces removed by an explosion in atomic
+}
+
+// Precomputed attacks from each square
+var (
+	knightAttacks [64]bitboard
+	kingAttacks   [64]bitboard
+	pawnAttacks   [2][64]bitboard // indexed by the colour of the attacking pawn
+)
+
+var (
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func init() {
+	knightSteps := [][2]int{{-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}, {1, -2}, {2, -1}, {2, 1}, {1, 2}}
+	kingSteps := [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	for sq := 0; sq < 64; sq++ {
+		knightAttacks[sq] = stepAttacks(sq, knightSteps)
+		kingAttacks[sq] = stepAttacks(sq, kingSteps)
+		pawnAttacks[white][sq] = stepAttacks(sq, [][2]int{{-1, 1}, {1, 1}})
+		pawnAttacks[black][sq] = stepAttacks(sq, [][2]int{{-1, -1}, {1, -1}})
+	}
+}
+
+// Returns the squares reached by taking one of the steps from the square
+func stepAttacks(sq int, steps [][2]int) bitboard {
+	var attacks bitboard
+	file, rank := sq%8, sq/8
+	for _, step := range steps {
+		f, r := file+step[0], rank+step[1]
+		if f >= 0 && f < 8 && r >= 0 && r < 8 {
+			attacks |= 1 << (r*8 + f)
+		}
+	}
+	return attacks
+}
+
+// Returns the squares attacked by a piece sliding in the directions until it is blocked
+func slidingAttacks(sq int, occupied bitboard, directions [4][2]int) bitboard {
+	var attacks bitboard
+	file, rank := sq%8, sq/8
+	for _, direction := range directions {
+		f, r := file+direction[0], rank+direction[1]
+		for f >= 0 && f < 8 && r >= 0 && r < 8 {
+			bit := bitboard(1) << (r*8 + f)
+			attacks |= bit
+			if occupied&bit != 0 {
+				break
+			}
+			f, r = f+direction[0], r+direction[1]
+		}
+	}
+	return attacks
+}
+
+// Converts a file and rank to a square number
+func square(file rune, rank int) int {
+	return (rank-1)*8 + fileToInt(file) - 1
+}
+
+// Converts a square number to a file and rank
+func squareToFileRank(sq int) (rune, int) {
+	return intToFile(sq%8 + 1), sq/8 + 1
+}
+
+// Converts "white" or "black" to a colour index
+func colorIndex(color string) int {
+	if color == "black" {
+		return black
+	}
+	return white
+}
+
+// Converts a colour index to "white" or "black"
+func colorName(color int) string {
+	if color == black {
+		return "black"
+	}
+	return "white"
+}
+
+// Creates a position from the board and side to move of the game
+//
+// The castling rights are given by the kings and rooks that haven't moved
+func newPosition(g *Game) *position {
+	p := &position{epSquare: noSquare, variant: g.Variant}
+	for i, row := range g.Board.Squares {
+		for j, piece := range row {
+			if piece == nil {
+				continue
+			}
+			p.put(colorIndex(piece.Color), piece.PieceType, i*8+j)
+		}
+	}
+	p.turn = colorIndex(g.Turn)
+	// the castling rights are ordered the same as castlingSides
+	for i, side := range castlingSides {
+		kingFile, rookFile, ok := g.Board.castlingFiles(side.color, side.kingside)
+		if !ok {
+			continue
+		}
+		right := uint8(1) << i
+		rank := backRank(side.color)
+		p.castling |= right
+		p.castlingRooks[i] = square(rookFile, rank)
+		p.castlingMasks[square(kingFile, rank)] |= right
+		p.castlingMasks[square(rookFile, rank)] |= right
+	}
+	return p
+}
+
+// Sets the squares of the board to the pieces of the position
+//
+// Kings and rooks are marked as moved unless they can still castle, so the
+// board gives the same castling rights as the position
+func (p *position) fillBoard(b *Board) {
+	b.Squares = [8][8]*Piece{}
+	for color := white; color <= black; color++ {
+		for pieceType := King; pieceType <= Pawn; pieceType++ {
+			for pieces := p.pieces[color][pieceType]; pieces != 0; pieces &= pieces - 1 {
+				sq := bits.TrailingZeros64(uint64(pieces))
+				b.Squares[sq/8][sq%8] = &Piece{
+					PieceType: PieceType(pieceType),
+					Color:     colorName(color),
+					Active:    true,
+					Moved:     pieceType == King || pieceType == Rook,
+				}
+			}
+		}
+	}
+	// the castling rights are ordered the same as castlingSides
+	for i, rook := range p.castlingRooks {
+		if p.castling&(1<<i) == 0 {
+			continue
+		}
+		king := bits.TrailingZeros64(uint64(p.pieces[i/2][King]))
+		b.Squares[rook/8][rook%8].Moved = false
+		b.Squares[king/8][king%8].Moved = false
+	}
+}
+
+// Adds or removes a piece from a square
+func (p *position) put(color int, pieceType PieceType, sq int) {
+	bit := bitboard(1) << sq
+	p.pieces[color][pieceType] ^= bit
+	p.occupied[color] ^= bit
+}
+
+// Returns the type of the piece of the given colour on the square
+func (p *position) pieceAt(color int, sq int) (PieceType, bool) {
+	bit := bitboard(1) << sq
+	if p.occupied[color]&bit == 0 {
+		return 0, false
+	}
+	for pieceType, pieces := range p.pieces[color] {
+		if pieces&bit != 0 {
+			return PieceType(pieceType), true
+		}
+	}
+	return 0, false
+}
+
+// Returns true if the square is attacked by any piece of the given colour
+func (p *position) isAttacked(sq int, byColor int) bool {
+	pieces := &p.pieces[byColor]
+	if pawnAttacks[1-byColor][sq]&pieces[Pawn] != 0 ||
+		knightAttacks[sq]&pieces[Knight] != 0 ||
+		kingAttacks[sq]&pieces[King] != 0 {
+		return true
+	}
+	occupied := p.occupied[white] | p.occupied[black]
+	if slidingAttacks(sq, occupied, bishopDirections)&(pieces[Bishop]|pieces[Queen]) != 0 {
+		return true
+	}
+	return slidingAttacks(sq, occupied, rookDirections)&(pieces[Rook]|pieces[Queen]) != 0
+}
+
+// Returns true if the king of the given colour is attacked
+//
+// In atomic a king next to the other king can't be attacked, as capturing it
+// would explode both kings
+func (p *position) kingAttacked(color int) bool {
+	king := p.pieces[color][King]
+	if king == 0 {
+		return false
+	}
+	sq := bits.TrailingZeros64(uint64(king))
+	if p.variant == Atomic && kingAttacks[sq]&p.pieces[1-color][King] != 0 {
+		return false
+	}
+	return p.isAttacked(sq, 1-color)
+}
+
+// Returns true if the side to move is in check
+func (p *position) inCheck() bool {
+	return p.kingAttacked(p.turn)
+}
+
+// Returns the legal moves for the side to move
+//
+// There are none once the side to move has lost by the rules of the variant
+func (p *position) legalMoves() []posMove {
+	if p.variantLoss() != InProgress {
+		return nil
+	}
+	moves := p.pseudoLegalMoves(make([]posMove, 0, 64))
+	legal := moves[:0]
+	for _, move := range moves {
+		undo := p.makeMove(move)
+		if !p.movedIntoCheck() {
+			legal = append(legal, move)
+		}
+		p.unmakeMove(move, undo)
+	}
+	return legal
+}
+
+// Appends the moves for the side to move that don't consider whether the king is left in check
+//
+// Castling is only generated if the king doesn't move out of or through check.
+// In atomic kings can't capture
+func (p *position) pseudoLegalMoves(moves []posMove) []posMove {
+	us, them := p.turn, 1-p.turn
+	own, enemy := p.occupied[us], p.occupied[them]
+	occupied := own | enemy
+	moves = p.pawnMoves(moves, occupied)
+	for pieceType := King; pieceType <= Knight; pieceType++ {
+		for pieces := p.pieces[us][pieceType]; pieces != 0; pieces &= pieces - 1 {
+			from := bits.TrailingZeros64(uint64(pieces))
+			var attacks bitboard
+			switch pieceType {
+			case King:
+				attacks = kingAttacks[from]
+			case Queen:
+				attacks = slidingAttacks(from, occupied, rookDirections) | slidingAttacks(from, occupied, bishopDirections)
+			case Rook:
+				attacks = slidingAttacks(from, occupied, rookDirections)
+			case Bishop:
+				attacks = slidingAttacks(from, occupied, bishopDirections)
+			case Knight:
+				attacks = knightAttacks[from]
+			}
+			if pieceType == King && p.variant == Atomic {
+				attacks &^= enemy
+			}
+			moves = p.appendTargets(moves, PieceType(pieceType), from, attacks&^own)
+		}
+	}
+	return p.castlingMoves(moves, occupied)
+}
+
+// Appends a move from the square to each of the targets
+func (p *position) appendTargets(moves []posMove, pieceType PieceType, from int, targets bitboard) []posMove {
+	for ; targets != 0; targets &= targets - 1 {
+		to := bits.TrailingZeros64(uint64(targets))
+		move := posMove{from: from, to: to, piece: pieceType}
+		if captured, ok := p.pieceAt(1-p.turn, to); ok {
+			move.captured = captured
+			move.flags |= moveCapture
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// Appends the pawn moves for the side to move
+func (p *position) pawnMoves(moves []posMove, occupied bitboard) []posMove {
+	us, them := p.turn, 1-p.turn
+	forward, startRank, lastRank := 8, 1, 7
+	if us == black {
+		forward, startRank, lastRank = -8, 6, 0
+	}
+	appendPawnMove := func(move posMove) {
+		if move.to/8 != lastRank {
+			moves = append(moves, move)
+			return
+		}
+		move.flags |= movePromotion
+		for _, promotion := range []PieceType{Knight, Bishop, Rook, Queen} {
+			move.promotion = promotion
+			moves = append(moves, move)
+		}
+	}
+	for pawns := p.pieces[us][Pawn]; pawns != 0; pawns &= pawns - 1 {
+		from := bits.TrailingZeros64(uint64(pawns))
+		// Forward, for pawns that aren't on the last rank
+		to := from + forward
+		if to >= 0 && to < 64 && occupied&(1<<to) == 0 {
+			appendPawnMove(posMove{from: from, to: to, piece: Pawn})
+			if from/8 == startRank && occupied&(1<<(to+forward)) == 0 {
+				moves = append(moves, posMove{from: from, to: to + forward, piece: Pawn, flags: moveDoublePush})
+			}
+		}
+		// Captures
+		for targets := pawnAttacks[us][from] & p.occupied[them]; targets != 0; targets &= targets - 1 {
+			to := bits.TrailingZeros64(uint64(targets))
+			captured, _ := p.pieceAt(them, to)
+			appendPawnMove(posMove{from: from, to: to, piece: Pawn, captured: captured, flags: moveCapture})
+		}
+		if p.epSquare != noSquare && pawnAttacks[us][from]&(1<<p.epSquare) != 0 {
+			moves = append(moves, posMove{
+				from:     from,
+				to:       p.epSquare,
+				piece:    Pawn,
+				captured: Pawn,
+				flags:    moveCapture | moveEnPassant,
+			})
+		}
+	}
+	return moves
+}
+
+// Appends the castling moves for the side to move
+//
+// The squares the king and rook pass through and land on must be empty apart from
+// the king and rook themselves, and the king can't start on, pass through or land
+// on an attacked square. Whether the king is attacked once the rook has moved is
+// left to the legality check. In atomic the king can pass through attacked squares
+// next to the other king
+func (p *position) castlingMoves(moves []posMove, occupied bitboard) []posMove {
+	us, them := p.turn, 1-p.turn
+	first := 0
+	if us == black {
+		first = 2
+	}
+	rights := uint8(1)<<first | uint8(1)<<(first+1)
+	if p.castling&rights == 0 || p.pieces[us][King] == 0 {
+		return moves
+	}
+	kingFrom := bits.TrailingZeros64(uint64(p.pieces[us][King]))
+	if p.inCheck() {
+		return moves
+	}
+	var unattackable bitboard
+	if p.variant == Atomic {
+		for kings := p.pieces[them][King]; kings != 0; kings &= kings - 1 {
+			unattackable |= kingAttacks[bits.TrailingZeros64(uint64(kings))]
+		}
+	}
+	for i := first; i < first+2; i++ {
+		if p.castling&(1<<i) == 0 {
+			continue
+		}
+		move := posMove{from: kingFrom, to: p.castlingRooks[i], piece: King, flags: moveCastle}
+		kingTo, rookTo := castlingTargets(move)
+		blockers := occupied &^ (1<<move.from | 1<<move.to)
+		if blockers&(rankSpan(move.from, kingTo)|rankSpan(move.to, rookTo)) != 0 {
+			continue
+		}
+		safe := true
+		for path := rankSpan(move.from, kingTo) &^ unattackable; path != 0 && safe; path &= path - 1 {
+			safe = !p.isAttacked(bits.TrailingZeros64(uint64(path)), them)
+		}
+		if safe {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+// Returns the squares the king and rook move to when castling
+func castlingTargets(move posMove) (kingTo, rookTo int) {
+	rank := move.from / 8 * 8
+	if move.to > move.from {
+		return rank + 6, rank + 5
+	}
+	return rank + 2, rank + 3
+}
+
+// Returns the squares from one square to another on the same rank, inclusive
+func rankSpan(from, to int) bitboard {
+	var span bitboard
+	for sq := min(from, to); sq <= max(from, to); sq++ {
+		span |= 1 << sq
+	}
+	return span
+}
+
+// Returns the square of the pawn captured en passant
+func (p *position) enPassantCaptureSquare(move posMove) int {
+	if move.to > move.from {
+		return move.to - 8
+	}
+	return move.to + 8
+}
+
+// Plays a move, returning the state needed to unmake it
+func (p *position) makeMove(move posMove) posUndo {
+	undo := posUndo{castling: p.castling, epSquare: p.epSquare, checks: p.checks}
+	us, them := p.turn, 1-p.turn
+	if move.flags&moveCastle != 0 {
+		// both pieces are lifted first as the king or rook can land on the other's square
+		kingTo, rookTo := castlingTargets(move)
+		p.put(us, King, move.from)
+		p.put(us, Rook, move.to)
+		p.put(us, King, kingTo)
+		p.put(us, Rook, rookTo)
+	} else {
+		if move.flags&moveEnPassant != 0 {
+			p.put(them, Pawn, p.enPassantCaptureSquare(move))
+		} else if move.flags&moveCapture != 0 {
+			p.put(them, move.captured, move.to)
+		}
+		p.put(us, move.piece, move.from)
+		if move.flags&movePromotion != 0 {
+			p.put(us, move.promotion, move.to)
+		} else {
+			p.put(us, move.piece, move.to)
+		}
+	}
+	p.castling &^= p.castlingMasks[move.from] | p.castlingMasks[move.to]
+	if p.variant == Atomic && move.flags&moveCapture != 0 {
+		undo.exploded = p.explode(move.to)
+	}
+	p.epSquare = noSquare
+	if move.flags&moveDoublePush != 0 {
+		p.epSquare = (move.from + move.to) / 2
+	}
+	p.turn = them
+	if p.variant == ThreeCheck && p.inCheck() {
+		p.checks[us]++
+	}
+	return undo
+}
+
+// Takes back a move played with makeMove
+func (p *position) unmakeMove(move posMove, undo posUndo) {
+	p.turn = 1 - p.turn
+	us, them := p.turn, 1-p.turn
+	p.unexplode(undo.exploded)
+	if move.flags&moveCastle != 0 {
+		kingTo, rookTo := castlingTargets(move)
+		p.put(us, King, kingTo)
+		p.put(us, Rook, rookTo)
+		p.put(us, King, move.from)
+		p.put(us, Rook, move.to)
+	} else {
+		if move.flags&movePromotion != 0 {
+			p.put(us, move.promotion, move.to)
+		} else {
+			p.put(us, move.piece, move.to)
+		}
+		p.put(us, move.piece, move.from)
+		if move.flags&moveEnPassant != 0 {
+			p.put(them, Pawn, p.enPassantCaptureSquare(move))
+		} else if move.flags&moveCapture != 0 {
+			p.put(them, move.captured, move.to)
+		}
+	}
+	p.castling = undo.castling
+	p.epSquare = undo.epSquare
+	p.checks = undo.checks
+}
+
+// Counts the leaf nodes of the move tree to the given depth
+func (p *position) perft(depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := p.legalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, move := range moves {
+		undo := p.makeMove(move)
+		nodes += p.perft(depth - 1)
+		p.unmakeMove(move, undo)
+	}
+	return nodes
+}
+
+// Converts a move in the position to a Move
+func (p *position) toMove(move posMove) Move {
+	fromFile, fromRank := squareToFileRank(move.from)
+	toFile, toRank := squareToFileRank(move.to)
+	if move.flags&moveCastle != 0 {
+		castle := "short"
+		if move.to < move.from {
+			castle = "long"
+		}
+		return Move{FromFile: fromFile, FromRank: fromRank, ToFile: toFile, ToRank: toRank, Castle: castle}
+	}
+	piece, _ := (&Piece{PieceType: move.piece}).getSymbol()
+	m := Move{
+		Piece:    piece,
+		FromFile: fromFile,
+		FromRank: fromRank,
+		ToFile:   toFile,
+		ToRank:   toRank,
+	}
+	if move.flags&moveCapture != 0 {
+		m.Capture = 'x'
+	}
+	if move.flags&movePromotion != 0 {
+		m.Promotion, _ = (&Piece{PieceType: move.promotion}).getSymbol()
+	}
+	return m
+}
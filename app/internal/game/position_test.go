@@ -0,0 +1,130 @@
+package game
+
+import "testing"
+
+func TestIsSquareAttacked(t *testing.T) {
+	g, err := NewGameFromFEN("4k3/8/8/3p4/8/2N5/8/R3K2B w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		file     rune
+		rank     int
+		color    string
+		expected bool
+	}{
+		{'c', 4, "black", true},  // pawn
+		{'e', 4, "black", true},  // pawn
+		{'d', 4, "black", false}, // pawns don't attack forwards
+		{'d', 5, "white", true},  // knight
+		{'a', 8, "white", true},  // rook
+		{'d', 1, "white", true},  // rook along the rank
+		{'c', 6, "white", false}, // bishop blocked by the pawn
+		{'d', 5, "black", false},
+		{'d', 7, "black", true}, // king
+		{'f', 2, "white", true}, // king
+	}
+
+	for _, tt := range tests {
+		color := white
+		if tt.color == "black" {
+			color = black
+		}
+		actual := newPosition(g).isAttacked(square(tt.file, tt.rank), color)
+		if actual != tt.expected {
+			t.Errorf("Expected %c%d attacked by %s: %v, got %v", tt.file, tt.rank, tt.color, tt.expected, actual)
+		}
+	}
+}
+
+func TestMakeUnmakeMove(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			pos := newPosition(g)
+			original := *pos
+			for _, move := range pos.legalMoves() {
+				undo := pos.makeMove(move)
+				if pos.turn == original.turn {
+					t.Errorf("Expected turn to change after %v", pos.toMove(move))
+				}
+				pos.unmakeMove(move, undo)
+				if *pos != original {
+					t.Errorf("Expected position to be restored after %v", pos.toMove(move))
+				}
+			}
+		})
+	}
+}
+
+func TestPawnOnLastRank(t *testing.T) {
+	// FENs with pawns on the back ranks are rejected, but the position must
+	// still not generate moves off the board for them
+	g, err := NewGameFromFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pos := newPosition(g)
+	pos.put(white, Pawn, square('a', 8))
+	pos.put(black, Pawn, square('h', 1))
+	for _, turn := range []int{white, black} {
+		pos.turn = turn
+		for _, move := range pos.legalMoves() {
+			if move.piece == Pawn {
+				t.Errorf("Expected no moves for the pawn, got %v", pos.toMove(move))
+			}
+		}
+		if pos.perft(2) == 0 {
+			t.Errorf("Expected the kings to have moves")
+		}
+	}
+}
+
+func TestPositionMatchesGame(t *testing.T) {
+	// Playing moves on the game and the position gives the same position
+	g := NewGame()
+	pos := newPosition(g)
+	for _, uci := range []string{"e2e4", "d7d5", "e4d5", "c7c5", "d5c6", "g8f6", "c6b7", "e8d7", "b7a8q", "d8b6", "g1f3", "b6b2", "f1e2", "b2c1", "e1h1"} {
+		var found bool
+		for _, move := range pos.legalMoves() {
+			if moveUCI, _ := pos.toMove(move).UCInotation(); moveUCI == uci {
+				pos.makeMove(move)
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Expected %s to be legal", uci)
+		}
+		_, err := g.MoveUCI(uci)
+		if err != nil {
+			t.Fatalf("Unexpected error on %s: %v", uci, err)
+		}
+		if !samePosition(pos, g.pos) {
+			t.Errorf("Expected position to match the game after %s", uci)
+		}
+		// the board filled from the game's position gives back the same position
+		fromBoard := newPosition(g)
+		fromBoard.epSquare = g.pos.epSquare
+		if !samePosition(pos, fromBoard) {
+			t.Errorf("Expected the board to match the position after %s", uci)
+		}
+	}
+}
+
+// Compares positions, ignoring the castling squares of rights that have been lost
+func samePosition(a, b *position) bool {
+	if a.pieces != b.pieces || a.occupied != b.occupied || a.turn != b.turn ||
+		a.castling != b.castling || a.epSquare != b.epSquare {
+		return false
+	}
+	for i := range a.castlingRooks {
+		if a.castling&(1<<i) != 0 && a.castlingRooks[i] != b.castlingRooks[i] {
+			return false
+		}
+	}
+	return true
+}
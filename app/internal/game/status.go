@@ -0,0 +1,177 @@
+package game
+
+// The state of the game for the side to move
+type Status int
+
+const (
+	InProgress Status = iota
+	Checkmate
+	Stalemate
+	FivefoldRepetition   // drawn automatically
+	SeventyFiveMoveRule  // drawn automatically
+	InsufficientMaterial // drawn automatically
+	ThreefoldRepetition  // either player can claim a draw
+	FiftyMoveRule        // either player can claim a draw
+	HillReached          // the other king reached the centre in King of the Hill
+	ThirdCheck           // the other side gave a third check in three-check
+	KingExploded         // the king was exploded in atomic
+)
+
+// Halfmoves without a capture or pawn move after which the game can be or is drawn
+const (
+	fiftyMoveHalfmoves       = 100
+	seventyFiveMoveHalfmoves = 150
+)
+
+// Returns the status as shown alongside the result e.g. "1-0 checkmate"
+func (s Status) String() string {
+	switch s {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case FivefoldRepetition:
+		return "fivefold repetition"
+	case SeventyFiveMoveRule:
+		return "seventy-five-move rule"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	case FiftyMoveRule:
+		return "fifty-move rule"
+	case HillReached:
+		return "king of the hill"
+	case ThirdCheck:
+		return "third check"
+	case KingExploded:
+		return "king exploded"
+	}
+	return "in progress"
+}
+
+// Returns true if the status is a draw
+func (s Status) IsDraw() bool {
+	return s != InProgress && !s.IsDecisive()
+}
+
+// Returns true if the side to move has lost
+func (s Status) IsDecisive() bool {
+	switch s {
+	case Checkmate, HillReached, ThirdCheck, KingExploded:
+		return true
+	}
+	return false
+}
+
+// Returns true if the draw must be claimed by a player rather than ending the game
+func (s Status) IsClaimable() bool {
+	return s == ThreefoldRepetition || s == FiftyMoveRule
+}
+
+// Returns true if the side to move is in check
+func (g *Game) InCheck() bool {
+	return g.position().inCheck()
+}
+
+// Returns the status of the game for the side to move
+//
+// Wins by the rules of the variant and checkmate take precedence over the draw
+// rules, and draws that end the game take precedence over draws that must be claimed
+func (g *Game) Status() Status {
+	if status := g.position().variantLoss(); status != InProgress {
+		return status
+	}
+	if len(g.legalMoves()) == 0 {
+		if g.InCheck() {
+			return Checkmate
+		}
+		return Stalemate
+	}
+	repetitions := g.repetitions()
+	switch {
+	case repetitions >= 5:
+		return FivefoldRepetition
+	case g.HalfmoveClock >= seventyFiveMoveHalfmoves:
+		return SeventyFiveMoveRule
+	case g.insufficientMaterial():
+		return InsufficientMaterial
+	case repetitions >= 3:
+		return ThreefoldRepetition
+	case g.HalfmoveClock >= fiftyMoveHalfmoves:
+		return FiftyMoveRule
+	}
+	return InProgress
+}
+
+// Returns the number of times the current position has been reached
+func (g *Game) repetitions() int {
+	if len(g.positions) == 0 {
+		return 1
+	}
+	current := g.positions[len(g.positions)-1]
+	count := 0
+	for _, position := range g.positions {
+		if position == current {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns a key identifying the position for repetition
+//
+// Positions are the same if the same pieces are on the same squares with the same
+// side to move, castling rights and en passant captures available. The hash counts
+// an en passant square whenever a pawn stands ready to capture, so it is removed
+// when the capture isn't legal
+func (g *Game) positionKey() uint64 {
+	key := g.Hash()
+	if enPassant := g.pos.enPassantHash(); enPassant != 0 && !g.canCaptureEnPassant() {
+		key ^= enPassant
+	}
+	return key
+}
+
+// Returns true if the side to move has a legal en passant capture
+func (g *Game) canCaptureEnPassant() bool {
+	for _, move := range g.legalMoves() {
+		if move.flags&moveEnPassant != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the result of the game as written in PGN
+//
+// 1-0 or 0-1 for a win, 1/2-1/2 for a draw and * for a game in progress
+//
+// Draws that can be claimed are counted as claimed
+func (g *Game) Result() string {
+	status := g.Status()
+	switch {
+	case status.IsDecisive():
+		if g.Turn == "white" {
+			return "0-1"
+		}
+		return "1-0"
+	case status.IsDraw():
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// Returns # if the side to move is checkmated, + if they are in check
+// and 0 otherwise
+//
+// A check that ends the game by the rules of the variant isn't checkmate
+func (p *position) checkStatus() rune {
+	if !p.inCheck() {
+		return 0
+	}
+	if p.variantLoss() == InProgress && len(p.legalMoves()) == 0 {
+		return '#'
+	}
+	return '+'
+}
@@ -0,0 +1,249 @@
+package game
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		fen     string
+		status  Status
+		result  string
+		inCheck bool
+	}{
+		{StartingFEN, InProgress, "*", false},
+		// Fool's mate
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Checkmate, "0-1", true},
+		// Back rank mate
+		{"3R2k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", Checkmate, "1-0", true},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", Stalemate, "1/2-1/2", false},
+		{"4k3/8/8/8/8/8/8/4K2r w - - 0 1", InProgress, "*", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if g.Status() != tt.status {
+				t.Errorf("Expected status %v, got %v", tt.status, g.Status())
+			}
+			if g.Result() != tt.result {
+				t.Errorf("Expected result %s, got %s", tt.result, g.Result())
+			}
+			if g.InCheck() != tt.inCheck {
+				t.Errorf("Expected in check %v, got %v", tt.inCheck, g.InCheck())
+			}
+		})
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	g := NewGame()
+	tests := []struct {
+		move     string
+		expected rune
+	}{
+		// Check marks in the input are ignored
+		{"e4+", 0},
+		{"e5", 0},
+		{"Bc4", 0},
+		{"Nc6", 0},
+		{"Qh5", 0},
+		{"Nf6", 0},
+		{"Bxf7", '+'},
+		{"Ke7", 0},
+		{"Qxe5", '+'},
+		{"Kxf7", 0},
+		{"Qf5", 0},
+		{"Ke7", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.move, func(t *testing.T) {
+			move, err := g.Move(tt.move)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if move.CheckStatus != tt.expected {
+				t.Errorf("Expected check status %q, got %q", tt.expected, move.CheckStatus)
+			}
+			if g.MoveHistory[len(g.MoveHistory)-1] != move {
+				t.Errorf("Expected move history to match the returned move")
+			}
+		})
+	}
+
+	// Scholar's mate
+	g = NewGame()
+	err := g.Moves([]string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	move, err := g.Move("Qxf7")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if move.CheckStatus != '#' {
+		t.Errorf("Expected checkmate, got %q", move.CheckStatus)
+	}
+	if g.Status() != Checkmate || g.Result() != "1-0" {
+		t.Errorf("Expected 1-0 checkmate, got %s %v", g.Result(), g.Status())
+	}
+
+	// Fool's mate, the previous double pawn move doesn't allow en passant
+	g = NewGame()
+	err = g.Moves([]string{"f3", "e5", "g4"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	move, err = g.Move("Qh4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if move.CheckStatus != '#' {
+		t.Errorf("Expected checkmate, got %q", move.CheckStatus)
+	}
+}
+
+func TestSANCheck(t *testing.T) {
+	g, err := NewGameFromFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		move     Move
+		expected string
+	}{
+		{Move{Piece: 'R', FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 8}, "Ra8#"},
+		{Move{Piece: 'R', FromFile: 'a', FromRank: 1, ToFile: 'a', ToRank: 7}, "Ra7"},
+		{Move{Piece: 'K', FromFile: 'g', FromRank: 1, ToFile: 'f', ToRank: 2}, "Kf2"},
+	}
+
+	for _, tt := range tests {
+		actual, err := g.SAN(tt.move)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if actual != tt.expected {
+			t.Errorf("Expected: %s, got: %s", tt.expected, actual)
+		}
+	}
+}
+
+func TestStatusDrawRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		moves  []string
+		status Status
+	}{
+		{
+			name:   "threefold repetition",
+			fen:    StartingFEN,
+			moves:  []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"},
+			status: ThreefoldRepetition,
+		},
+		{
+			name:   "twofold repetition",
+			fen:    StartingFEN,
+			moves:  []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1"},
+			status: InProgress,
+		},
+		{
+			name: "fivefold repetition",
+			fen:  StartingFEN,
+			moves: []string{
+				"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8",
+				"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8",
+			},
+			status: FivefoldRepetition,
+		},
+		{
+			// The en passant target square after e4 doesn't count as no capture is possible
+			name:   "repetition with unusable en passant square",
+			fen:    StartingFEN,
+			moves:  []string{"e4", "Nf6", "Nf3", "Ng8", "Ng1", "Nf6", "Nf3", "Ng8", "Ng1"},
+			status: ThreefoldRepetition,
+		},
+		{
+			// The first position allowed an en passant capture so it is different
+			name:   "repetition with usable en passant square",
+			fen:    "4k3/8/8/8/3p4/8/4P3/4K1N1 w - - 0 1",
+			moves:  []string{"e4", "Kd7", "Nf3", "Ke8", "Ng1", "Kd7", "Nf3", "Ke8", "Ng1"},
+			status: InProgress,
+		},
+		{
+			name:   "fifty-move rule",
+			fen:    "4k3/8/8/8/8/8/4P3/4K3 w - - 99 80",
+			moves:  []string{"Kd2"},
+			status: FiftyMoveRule,
+		},
+		{
+			name:   "fifty-move rule reset by a pawn move",
+			fen:    "4k3/8/8/8/8/8/4P3/4K3 w - - 99 80",
+			moves:  []string{"e4"},
+			status: InProgress,
+		},
+		{
+			name:   "seventy-five-move rule",
+			fen:    "4k3/8/8/8/8/8/4P3/4K3 w - - 149 80",
+			moves:  []string{"Kd2"},
+			status: SeventyFiveMoveRule,
+		},
+		{
+			name:   "checkmate takes precedence",
+			fen:    "6k1/5ppp/8/8/8/8/8/R5K1 w - - 149 80",
+			moves:  []string{"Ra8"},
+			status: Checkmate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			err = g.Moves(tt.moves)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if g.Status() != tt.status {
+				t.Errorf("Expected status %v, got %v", tt.status, g.Status())
+			}
+			if tt.status.IsDraw() && g.Result() != "1/2-1/2" {
+				t.Errorf("Expected draw, got %s", g.Result())
+			}
+		})
+	}
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/1N2K3 b - - 0 1", true},
+		// Bishops on the same colour squares
+		{"2b1k3/8/8/8/8/8/8/4KB2 w - - 0 1", true},
+		// Bishops on opposite colour squares
+		{"3bk3/8/8/8/8/8/8/4KB2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fen, func(t *testing.T) {
+			g, err := NewGameFromFEN(tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (g.Status() == InsufficientMaterial) != tt.expected {
+				t.Errorf("Expected insufficient material: %v, got status %v", tt.expected, g.Status())
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNodeNotInTree = errors.New("node is not in the tree")
+
+// A position in a game tree, reached by playing the node's move from its parent
+type Node struct {
+	Move       Move     // move from the parent, unset for the root
+	Parent     *Node    // nil for the root
+	Children   []*Node  // the first child continues the line, the others are variations
+	PreComment string   // comment before the first move of a line
+	Comments   []string // comments following the move
+	NAGs       []int    // numeric annotation glyphs e.g. $1 for !
+}
+
+// A game with alternative lines of moves
+//
+// The mainline is found by following the first child of each node from the root
+type GameTree struct {
+	Root  *Node
+	start *Game // position before the first move
+}
+
+// Creates a tree with no moves played from the game's current position
+func NewGameTree(start *Game) *GameTree {
+	return &GameTree{
+		Root:  &Node{},
+		start: start.Clone(),
+	}
+}
+
+// Returns a copy of the position before the first move
+func (t *GameTree) Start() *Game {
+	return t.start.Clone()
+}
+
+// Returns the game after playing the moves leading to the node
+func (t *GameTree) GameAt(n *Node) (*Game, error) {
+	if n.root() != t.Root {
+		return nil, ErrNodeNotInTree
+	}
+	g := t.Start()
+	for i, node := range n.Path() {
+		_, err := g.PlayMove(node.Move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+	return g, nil
+}
+
+// Plays the move from the parent's position and adds it as the parent's last child
+//
+// If the parent already has a child with the same move, that child is returned instead
+func (t *GameTree) AddMove(parent *Node, move Move) (*Node, error) {
+	g, err := t.GameAt(parent)
+	if err != nil {
+		return nil, err
+	}
+	return parent.addMove(g, move)
+}
+
+// Plays a line of moves from the parent's position, adding each move after the last
+//
+// Returns the node of the last move
+func (t *GameTree) AddLine(parent *Node, moves []Move) (*Node, error) {
+	g, err := t.GameAt(parent)
+	if err != nil {
+		return nil, err
+	}
+	node := parent
+	for i, move := range moves {
+		node, err = node.addMove(g, move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+	return node, nil
+}
+
+// Returns the nodes of the mainline in the order they are played
+func (t *GameTree) Mainline() []*Node {
+	if len(t.Root.Children) == 0 {
+		return []*Node{}
+	}
+	return t.Root.Children[0].Line()
+}
+
+// Returns the SAN of the node's move e.g. Nf3
+func (t *GameTree) SAN(n *Node) (string, error) {
+	if n.Parent == nil {
+		return "", ErrNodeNotInTree
+	}
+	g, err := t.GameAt(n.Parent)
+	if err != nil {
+		return "", err
+	}
+	return g.SAN(n.Move)
+}
+
+// Plays the move on the game, which must be at the node's position,
+// and adds it as a child of the node
+func (n *Node) addMove(g *Game, move Move) (*Node, error) {
+	played, err := g.PlayMove(move)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range n.Children {
+		if sameMove(child.Move, played) {
+			return child, nil
+		}
+	}
+	child := &Node{Move: played, Parent: n}
+	n.Children = append(n.Children, child)
+	return child, nil
+}
+
+// Returns true if the moves go from and to the same squares
+func sameMove(a, b Move) bool {
+	if a.Castle != "" || b.Castle != "" {
+		return a.Castle == b.Castle
+	}
+	return a.FromFile == b.FromFile && a.FromRank == b.FromRank &&
+		a.ToFile == b.ToFile && a.ToRank == b.ToRank && a.Promotion == b.Promotion
+}
+
+// Returns the nodes from the first move to the node, excluding the root
+func (n *Node) Path() []*Node {
+	path := []*Node{}
+	for node := n; node.Parent != nil; node = node.Parent {
+		path = append(path, node)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Returns the node followed by the first child of each node after it
+func (n *Node) Line() []*Node {
+	line := []*Node{n}
+	for node := n; len(node.Children) > 0; node = node.Children[0] {
+		line = append(line, node.Children[0])
+	}
+	return line
+}
+
+// Returns the number of moves played to reach the node
+func (n *Node) Ply() int {
+	ply := 0
+	for node := n; node.Parent != nil; node = node.Parent {
+		ply++
+	}
+	return ply
+}
+
+// Returns true if the node is on the mainline
+func (n *Node) IsMainline() bool {
+	for node := n; node.Parent != nil; node = node.Parent {
+		if node.Parent.Children[0] != node {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the other children of the node's parent
+func (n *Node) Siblings() []*Node {
+	if n.Parent == nil {
+		return []*Node{}
+	}
+	siblings := []*Node{}
+	for _, child := range n.Parent.Children {
+		if child != n {
+			siblings = append(siblings, child)
+		}
+	}
+	return siblings
+}
+
+// Moves the node one place towards the front of its parent's children
+func (n *Node) Promote() {
+	if n.Parent == nil {
+		return
+	}
+	children := n.Parent.Children
+	for i := 1; i < len(children); i++ {
+		if children[i] == n {
+			children[i-1], children[i] = children[i], children[i-1]
+			return
+		}
+	}
+}
+
+// Makes the node and the nodes leading to it the first child of their parents
+//
+// The order of the other children is preserved
+func (n *Node) PromoteToMainline() {
+	for node := n; node.Parent != nil; node = node.Parent {
+		children := node.Parent.Children
+		for i := 1; i < len(children); i++ {
+			if children[i] == node {
+				copy(children[1:i+1], children[:i])
+				children[0] = node
+				break
+			}
+		}
+	}
+}
+
+// Removes the node and every move after it from the tree
+func (n *Node) Remove() {
+	if n.Parent == nil {
+		return
+	}
+	children := n.Parent.Children
+	for i, child := range children {
+		if child == n {
+			n.Parent.Children = append(children[:i:i], children[i+1:]...)
+			break
+		}
+	}
+	n.Parent = nil
+}
+
+// Returns the root of the tree the node is in
+func (n *Node) root() *Node {
+	node := n
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+// Creates a tree from the mainline and variations of the game
+func (pg *PGNGame) GameTree() (*GameTree, error) {
+	g, err := startingPosition(pg.Tags)
+	if err != nil {
+		return nil, err
+	}
+	t := NewGameTree(g)
+	err = t.addPGNLine(t.Root, g, pg.Moves)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Adds a line of PGN moves after the node, the game must be at the node's position
+func (t *GameTree) addPGNLine(parent *Node, g *Game, moves []*PGNMove) error {
+	for i, pgnMove := range moves {
+		previous := g.Clone()
+		node, err := parent.addMove(g, pgnMove.Move)
+		if err != nil {
+			return fmt.Errorf("move %d: %w", i+1, err)
+		}
+		if i == 0 {
+			node.PreComment = pgnMove.PreComment
+		}
+		node.Comments = append(node.Comments, pgnMove.Comments...)
+		node.NAGs = append(node.NAGs, pgnMove.NAGs...)
+		for _, variation := range pgnMove.Variations {
+			err = t.addPGNLine(parent, previous.Clone(), variation)
+			if err != nil {
+				return err
+			}
+		}
+		parent = node
+	}
+	return nil
+}
+
+// Returns the mainline of the tree as PGN moves, with the other lines as variations
+func (t *GameTree) PGNMoves() []*PGNMove {
+	if len(t.Root.Children) == 0 {
+		return []*PGNMove{}
+	}
+	moves := t.Root.Children[0].pgnLine()
+	for _, variation := range t.Root.Children[1:] {
+		moves[0].Variations = append(moves[0].Variations, variation.pgnLine())
+	}
+	return moves
+}
+
+// Returns the node and the moves after it as PGN moves
+//
+// Each move after the first has its parent's other children as variations,
+// the variations of the first move are left to the caller
+func (n *Node) pgnLine() []*PGNMove {
+	moves := []*PGNMove{}
+	for _, node := range n.Line() {
+		pgnMove := &PGNMove{
+			Move:       node.Move,
+			PreComment: node.PreComment,
+			Comments:   node.Comments,
+			NAGs:       node.NAGs,
+		}
+		if node != n {
+			for _, variation := range node.Parent.Children[1:] {
+				pgnMove.Variations = append(pgnMove.Variations, variation.pgnLine())
+			}
+		}
+		moves = append(moves, pgnMove)
+	}
+	return moves
+}
+
+// Returns the movetext of the tree without a result e.g. 1. e4 (1. d4) 1... e5
+func (t *GameTree) Movetext() (string, error) {
+	tokens, err := movetextTokens(t.Start(), t.PGNMoves())
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+// Creates a tree from movetext played from the starting position
+//
+// A result at the end of the movetext is ignored
+func ParseMovetext(start *Game, movetext string) (*GameTree, error) {
+	tokens, err := tokenizePGN(movetext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPGN, err)
+	}
+	p := &pgnParser{tokens: tokens}
+	g := start.Clone()
+	moves, err := p.parseLine(g, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPGN, err)
+	}
+	t := NewGameTree(start)
+	err = t.addPGNLine(t.Root, start.Clone(), moves)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,165 @@
+package game
+
+import "testing"
+
+// Adds moves given in any notation accepted by Game.Move after the node
+func addTestLine(t *testing.T, tree *GameTree, parent *Node, moveStrs ...string) *Node {
+	t.Helper()
+	g, err := tree.GameAt(parent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	moves := []Move{}
+	for _, moveStr := range moveStrs {
+		move, err := g.Move(moveStr)
+		if err != nil {
+			t.Fatalf("Unexpected error playing %s: %v", moveStr, err)
+		}
+		moves = append(moves, move)
+	}
+	node, err := tree.AddLine(parent, moves)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return node
+}
+
+func TestGameTreeAddMove(t *testing.T) {
+	tree := NewGameTree(NewGame())
+	e5 := addTestLine(t, tree, tree.Root, "e4", "e5")
+	c5 := addTestLine(t, tree, e5.Parent, "c5")
+	d4 := addTestLine(t, tree, tree.Root, "d4")
+
+	if len(tree.Mainline()) != 2 || tree.Mainline()[1] != e5 {
+		t.Errorf("Expected the mainline to be e4 e5, got %d moves", len(tree.Mainline()))
+	}
+	if len(e5.Parent.Children) != 2 || len(tree.Root.Children) != 2 {
+		t.Errorf("Expected two children after the root and e4")
+	}
+	if !e5.IsMainline() || c5.IsMainline() || d4.IsMainline() {
+		t.Errorf("Expected only e5 to be on the mainline")
+	}
+	if c5.Ply() != 2 || d4.Ply() != 1 {
+		t.Errorf("Expected plies 2 and 1, got %d and %d", c5.Ply(), d4.Ply())
+	}
+
+	// Adding a move that is already a child returns the existing node
+	again := addTestLine(t, tree, tree.Root, "e2e4", "c7c5")
+	if again != c5 {
+		t.Errorf("Expected the existing node to be reused")
+	}
+
+	g, err := tree.GameAt(c5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq c6 0 2"
+	if g.FEN() != expected {
+		t.Errorf("Expected FEN %s, got %s", expected, g.FEN())
+	}
+	san, err := tree.SAN(c5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if san != "c5" {
+		t.Errorf("Expected SAN c5, got %s", san)
+	}
+
+	_, err = tree.AddMove(d4, Move{FromFile: 'e', FromRank: 2, ToFile: 'e', ToRank: 4})
+	if err == nil {
+		t.Errorf("Expected an error adding an illegal move")
+	}
+	_, err = tree.GameAt(&Node{})
+	if err != ErrNodeNotInTree {
+		t.Errorf("Expected error %v, got %v", ErrNodeNotInTree, err)
+	}
+}
+
+func TestPromoteToMainline(t *testing.T) {
+	tree := NewGameTree(NewGame())
+	addTestLine(t, tree, tree.Root, "e4", "e5", "Nf3")
+	c4 := addTestLine(t, tree, tree.Root, "c4")
+	d4 := addTestLine(t, tree, tree.Root, "d4")
+	d5 := addTestLine(t, tree, d4, "d5")
+	nf6 := addTestLine(t, tree, d4, "Nf6")
+
+	nf6.PromoteToMainline()
+	mainline := tree.Mainline()
+	if len(mainline) != 2 || mainline[0] != d4 || mainline[1] != nf6 {
+		t.Fatalf("Expected the mainline to be d4 Nf6")
+	}
+	// The other children keep their order behind the promoted move
+	if tree.Root.Children[2] != c4 || d4.Children[1] != d5 {
+		t.Errorf("Expected the other children to keep their order")
+	}
+
+	c4.Promote()
+	if tree.Root.Children[1] != c4 {
+		t.Errorf("Expected c4 to be promoted above e4")
+	}
+	c4.Promote()
+	if tree.Root.Children[0] != c4 || !c4.IsMainline() {
+		t.Errorf("Expected c4 to be the mainline")
+	}
+
+	d4.Remove()
+	if len(tree.Root.Children) != 2 {
+		t.Errorf("Expected 2 children after removing d4, got %d", len(tree.Root.Children))
+	}
+	for _, child := range tree.Root.Children {
+		if child == d4 {
+			t.Errorf("Expected d4 to be removed")
+		}
+	}
+}
+
+func TestGameTreePGN(t *testing.T) {
+	pgn := `[Event "Test"]
+
+{Opening} 1. e4 $1 {Best by test} (1. d4 d5 (1... Nf6 2. c4) 2. c4) 1... e5 2. Nf3 (2. f4 exf4) 2... Nc6 *`
+	games, err := ReadPGN(pgn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tree, err := games[0].GameTree()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mainline := tree.Mainline()
+	if len(mainline) != 4 {
+		t.Fatalf("Expected 4 mainline moves, got %d", len(mainline))
+	}
+	e4 := mainline[0]
+	if e4.PreComment != "Opening" || len(e4.Comments) != 1 || len(e4.NAGs) != 1 || e4.NAGs[0] != 1 {
+		t.Errorf("Expected e4 to keep its comments and NAGs")
+	}
+	if len(tree.Root.Children) != 2 || len(tree.Root.Children[1].Children) != 2 {
+		t.Errorf("Expected 1. d4 to be a variation with 1... Nf6 as a sub-variation")
+	}
+
+	expected := "{Opening} 1. e4 $1 {Best by test} (1. d4 d5 (1... Nf6 2. c4) 2. c4) 1... e5 2. Nf3 (2. f4 exf4) 2... Nc6"
+	movetext, err := tree.Movetext()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if movetext != expected {
+		t.Errorf("Expected movetext %s, got %s", expected, movetext)
+	}
+
+	parsed, err := ParseMovetext(tree.Start(), movetext+" *")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reparsed, err := parsed.Movetext()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reparsed != movetext {
+		t.Errorf("Expected movetext %s after parsing, got %s", movetext, reparsed)
+	}
+
+	_, err = ParseMovetext(NewGame(), "1. e4 (1. e5)")
+	if err == nil {
+		t.Errorf("Expected an error parsing an illegal variation")
+	}
+}
@@ -0,0 +1,191 @@
+package game
+
+import (
+	"errors"
+	"math/bits"
+	"strings"
+)
+
+var ErrUnknownVariant = errors.New("unknown variant")
+
+// A set of rules that changes how the game is won, which moves are legal
+// and what happens when a piece is captured
+//
+// Chess960 changes only the starting position and castling, so it is tracked
+// separately by Game.Chess960 and can be combined with any variant
+type Variant int
+
+const (
+	Standard      Variant = iota
+	KingOfTheHill         // moving the king to one of the four centre squares wins
+	ThreeCheck            // giving check for the third time wins
+	Atomic                // captures explode, removing the capturing piece and every piece but pawns next to it
+)
+
+// Checks needed to win a three-check game
+const checksToWin = 3
+
+// The four centre squares, d4, e4, d5 and e5
+const hill bitboard = 1<<27 | 1<<28 | 1<<35 | 1<<36
+
+// Returns the name of the variant as written in the PGN Variant tag
+func (v Variant) String() string {
+	switch v {
+	case KingOfTheHill:
+		return "King of the Hill"
+	case ThreeCheck:
+		return "Three-check"
+	case Atomic:
+		return "Atomic"
+	}
+	return "Standard"
+}
+
+// Returns the name of the variant used by the UCI_Variant engine option
+func (v Variant) UCIName() string {
+	switch v {
+	case KingOfTheHill:
+		return "kingofthehill"
+	case ThreeCheck:
+		return "3check"
+	case Atomic:
+		return "atomic"
+	}
+	return "chess"
+}
+
+// Parses the name of a variant as written in the PGN Variant tag or used by UCI_Variant
+//
+// Case, spaces and hyphens are ignored. An empty name, "From Position" and the names
+// of Chess960 are standard chess
+func ParseVariant(name string) (Variant, error) {
+	if isChess960Variant(name) {
+		return Standard, nil
+	}
+	normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+	switch normalized {
+	case "", "standard", "chess", "fromposition":
+		return Standard, nil
+	case "kingofthehill", "koth":
+		return KingOfTheHill, nil
+	case "threecheck", "3check":
+		return ThreeCheck, nil
+	case "atomic":
+		return Atomic, nil
+	}
+	return Standard, ErrUnknownVariant
+}
+
+// Creates a new game of the variant from a position in Forsyth-Edwards Notation
+//
+// An empty fen starts from the initial position
+func NewVariantGame(variant Variant, fen string) (*Game, error) {
+	g := NewGame()
+	if fen != "" {
+		var err error
+		g, err = NewGameFromFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.Variant = variant
+	return g, nil
+}
+
+// Returns the status if the side to move has lost by the rules of the variant,
+// otherwise InProgress
+func (p *position) variantLoss() Status {
+	us, them := p.turn, 1-p.turn
+	switch p.variant {
+	case KingOfTheHill:
+		if p.pieces[them][King]&hill != 0 {
+			return HillReached
+		}
+	case ThreeCheck:
+		if p.checks[them] >= checksToWin {
+			return ThirdCheck
+		}
+	case Atomic:
+		if p.pieces[us][King] == 0 {
+			return KingExploded
+		}
+	}
+	return InProgress
+}
+
+// Returns true if the side that just moved has left its king in check
+//
+// In atomic the move is also illegal if it explodes their own king, but exploding
+// the other king wins even if their own king is left in check
+func (p *position) movedIntoCheck() bool {
+	us := 1 - p.turn
+	if p.variant != Atomic {
+		return p.kingAttacked(us)
+	}
+	if p.pieces[us][King] == 0 {
+		return true
+	}
+	if p.pieces[p.turn][King] == 0 {
+		return false
+	}
+	return p.kingAttacked(us)
+}
+
+// Removes the capturing piece on the square and every piece but pawns next to it
+//
+// Returns the pieces removed so they can be put back
+func (p *position) explode(sq int) [2][6]bitboard {
+	var exploded [2][6]bitboard
+	for color := white; color <= black; color++ {
+		for pieceType := King; pieceType <= Pawn; pieceType++ {
+			blast := bitboard(1) << sq
+			if pieceType != Pawn {
+				blast |= kingAttacks[sq]
+			}
+			removed := p.pieces[color][pieceType] & blast
+			p.pieces[color][pieceType] ^= removed
+			p.occupied[color] ^= removed
+			exploded[color][pieceType] = removed
+			for ; removed != 0; removed &= removed - 1 {
+				p.castling &^= p.castlingMasks[bits.TrailingZeros64(uint64(removed))]
+			}
+		}
+	}
+	return exploded
+}
+
+// Puts back the pieces removed by an explosion
+func (p *position) unexplode(exploded [2][6]bitboard) {
+	for color := white; color <= black; color++ {
+		for pieceType := King; pieceType <= Pawn; pieceType++ {
+			p.pieces[color][pieceType] ^= exploded[color][pieceType]
+			p.occupied[color] ^= exploded[color][pieceType]
+		}
+	}
+}
+
+// Returns true if there are no pieces but kings on the board
+func (b *Board) onlyKings() bool {
+	for _, row := range b.Squares {
+		for _, p := range row {
+			if p != nil && p.PieceType != King {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns true if neither player can win by the rules of the variant
+//
+// In King of the Hill either king can still walk to the centre, and three-check
+// and atomic games are only drawn once nothing but the kings remain
+func (g *Game) insufficientMaterial() bool {
+	switch g.Variant {
+	case KingOfTheHill:
+		return false
+	case ThreeCheck, Atomic:
+		return g.Board.onlyKings()
+	}
+	return g.Board.insufficientMaterial()
+}
@@ -0,0 +1,245 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Variant
+		err      error
+	}{
+		{"", Standard, nil},
+		{"Standard", Standard, nil},
+		{"From Position", Standard, nil},
+		{"Chess960", Standard, nil},
+		{"King of the Hill", KingOfTheHill, nil},
+		{"kingofthehill", KingOfTheHill, nil},
+		{"Three-check", ThreeCheck, nil},
+		{"3check", ThreeCheck, nil},
+		{"Atomic", Atomic, nil},
+		{"Crazyhouse", Standard, ErrUnknownVariant},
+	}
+
+	for _, tt := range tests {
+		variant, err := ParseVariant(tt.name)
+		if err != tt.err {
+			t.Errorf("Expected error %v for %q, got %v", tt.err, tt.name, err)
+		}
+		if variant != tt.expected {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.name, variant)
+		}
+	}
+}
+
+func TestVariantPerft(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		fen     string
+		nodes   []int // nodes at depth 1, 2, ...
+	}{
+		{"atomic initial", Atomic, StartingFEN, []int{20, 400, 8902, 197326}},
+		{"three-check initial", ThreeCheck, StartingFEN, []int{20, 400, 8902, 197281}},
+		// Moving the king to d4 or e4 ends the game
+		{"king of the hill", KingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1", []int{8, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewVariantGame(tt.variant, tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for depth, expected := range tt.nodes {
+				if actual := g.Perft(depth + 1); actual != expected {
+					t.Errorf("Expected %d nodes at depth %d, got %d", expected, depth+1, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	g, err := NewVariantGame(KingOfTheHill, "4k3/8/8/8/8/3K4/8/8 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = g.Move("Ke4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.Status() != HillReached {
+		t.Errorf("Expected status %v, got %v", HillReached, g.Status())
+	}
+	if g.Result() != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", g.Result())
+	}
+	if len(g.GetPossibleMoves()) != 0 {
+		t.Errorf("Expected no possible moves, got %d", len(g.GetPossibleMoves()))
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	g, err := NewGameFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 1+3 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.Variant != ThreeCheck {
+		t.Fatalf("Expected variant %v, got %v", ThreeCheck, g.Variant)
+	}
+	before := g.Clone()
+	err = g.Moves([]string{"e4", "f6"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	san, err := g.SAN(Move{Piece: 'Q', FromFile: 'd', FromRank: 1, ToFile: 'h', ToRank: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if san != "Qh5+" {
+		t.Errorf("Expected the third check to be written Qh5+, got %s", san)
+	}
+	_, err = g.Move("Qh5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.Status() != ThirdCheck {
+		t.Errorf("Expected status %v, got %v", ThirdCheck, g.Status())
+	}
+	if g.Result() != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", g.Result())
+	}
+	expected := "rnbqkbnr/ppppp1pp/5p2/7Q/4P3/8/PPPP1PPP/RNB1KBNR b KQkq - 0+3 1 2"
+	if g.FEN() != expected {
+		t.Errorf("Expected FEN %s, got %s", expected, g.FEN())
+	}
+	for range 3 {
+		_, err = g.Undo()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if g.FEN() != before.FEN() {
+		t.Errorf("Expected FEN %s after undoing, got %s", before.FEN(), g.FEN())
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	g, err := NewVariantGame(Atomic, "4k3/8/2nr4/2Pp4/4R3/1B6/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	before := g.FEN()
+	_, err = g.Move("Bxd5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The bishop, the pawn it captured and the pieces next to d5 are removed, apart from pawns
+	expected := "4k3/8/8/2P5/8/8/8/4K3 b - - 0 1"
+	if g.FEN() != expected {
+		t.Errorf("Expected FEN %s, got %s", expected, g.FEN())
+	}
+	if g.Hash() != boardHash(g) {
+		t.Errorf("Expected hash %x, got %x", boardHash(g), g.Hash())
+	}
+	if len(g.Board.captured) != 5 {
+		t.Errorf("Expected 5 captured pieces, got %d", len(g.Board.captured))
+	}
+	_, err = g.Undo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.FEN() != before {
+		t.Errorf("Expected FEN %s after undoing, got %s", before, g.FEN())
+	}
+	if g.Hash() != boardHash(g) {
+		t.Errorf("Expected hash %x after undoing, got %x", boardHash(g), g.Hash())
+	}
+}
+
+func TestAtomicLegality(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		illegal []string // moves in UCI notation
+		legal   []string
+	}{
+		{
+			// Capturing next to the king explodes it and kings can't capture
+			name:    "own king",
+			fen:     "4k3/8/8/8/8/8/3p4/3QK3 w - - 0 1",
+			illegal: []string{"d1d2", "e1d2"},
+			legal:   []string{"e1f2"},
+		},
+		{
+			// Exploding the other king wins even when the king is left in check
+			name:  "other king",
+			fen:   "4k3/4p3/8/8/5q2/8/8/4RK2 w - - 0 1",
+			legal: []string{"e1e7"},
+		},
+		{
+			// A king next to the other king can't be in check
+			name:  "connected kings",
+			fen:   "8/8/8/3kK3/8/8/8/3R4 b - - 0 1",
+			legal: []string{"d5d6", "d5d4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewVariantGame(Atomic, tt.fen)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			possible := ConvertMovesToUCINotation(g.GetPossibleMoves())
+			contains := func(uci string) bool {
+				for _, move := range possible {
+					if move == uci {
+						return true
+					}
+				}
+				return false
+			}
+			for _, uci := range tt.illegal {
+				if contains(uci) {
+					t.Errorf("Expected %s to be illegal", uci)
+				}
+			}
+			for _, uci := range tt.legal {
+				if !contains(uci) {
+					t.Errorf("Expected %s to be legal", uci)
+				}
+			}
+		})
+	}
+}
+
+func TestAtomicPGN(t *testing.T) {
+	pgn := `[Variant "Atomic"]
+
+1. e4 d5 2. exd5 Qxd2 0-1`
+	games, err := ReadPGN(pgn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g := games[0].Game
+	if g.Variant != Atomic {
+		t.Errorf("Expected variant %v, got %v", Atomic, g.Variant)
+	}
+	if g.Status() != KingExploded {
+		t.Errorf("Expected status %v, got %v", KingExploded, g.Status())
+	}
+	if g.Result() != "0-1" {
+		t.Errorf("Expected result 0-1, got %s", g.Result())
+	}
+	exported, err := games[0].PGN()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(exported, "[Variant \"Atomic\"]") || !strings.Contains(exported, "2. exd5 Qxd2 0-1") {
+		t.Errorf("Expected the atomic game to be exported, got %s", exported)
+	}
+}
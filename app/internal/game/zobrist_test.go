@@ -0,0 +1,76 @@
+package game
+
+import "testing"
+
+// Keys from the Polyglot book format specification
+func TestHash(t *testing.T) {
+	tests := []struct {
+		moves []string
+		fen   string
+		hash  uint64
+	}{
+		{nil, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0x463b96181691fc9c},
+		{[]string{"e2e4"}, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, "rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, "rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, "rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		g := NewGame()
+		for _, move := range tt.moves {
+			_, err := g.MoveUCI(move)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.moves, err)
+			}
+		}
+		if g.Hash() != tt.hash {
+			t.Errorf("%v: expected hash %016x, got %016x", tt.moves, tt.hash, g.Hash())
+		}
+		fromFEN, err := NewGameFromFEN(tt.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		if fromFEN.Hash() != tt.hash {
+			t.Errorf("%s: expected hash %016x, got %016x", tt.fen, tt.hash, fromFEN.Hash())
+		}
+	}
+}
+
+func TestHashIncremental(t *testing.T) {
+	g, err := NewGameFromFEN("r3k2r/1P1p4/8/4P3/8/8/8/R3K2R b KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := []string{"d7d5", "e5d6", "e8g8", "b7a8q", "f8a8", "e1c1", "a8a1", "c1d2"}
+	hashes := []uint64{g.Hash()}
+	for _, move := range moves {
+		_, err := g.MoveUCI(move)
+		if err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+		if g.Hash() != boardHash(g) {
+			t.Errorf("%s: incremental hash %016x doesn't match %016x", move, g.Hash(), boardHash(g))
+		}
+		hashes = append(hashes, g.Hash())
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		_, err := g.Undo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Hash() != hashes[i] || g.Hash() != boardHash(g) {
+			t.Errorf("undo %s: expected hash %016x, got %016x", moves[i], hashes[i], g.Hash())
+		}
+	}
+}
+
+// Calculates the hash of the position given by the board rather than the one moves are played in
+func boardHash(g *Game) uint64 {
+	pos := newPosition(g)
+	pos.epSquare, pos.checks = g.pos.epSquare, g.pos.checks
+	return pos.zobrist()
+}
@@ -14,6 +14,7 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
 )
 
 // Draw the evaluation bar
@@ -79,6 +80,13 @@ func (b *Board) drawAnalysis(gtx layout.Context) layout.Dimensions {
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 						return b.drawEvalGraph(gtx)
 					}),
+					// Win probability graph
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !b.showWDL {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: 10}.Layout(gtx, b.drawWDLGraph)
+					}),
 					// Spacer
 					layout.Rigid(layout.Spacer{Height: 20}.Layout),
 					// Eval info
@@ -92,23 +100,20 @@ func (b *Board) drawAnalysis(gtx layout.Context) layout.Dimensions {
 						if b.bestLines == nil {
 							return layout.Dimensions{}
 						}
-						if b.moves[b.stateNum].evals[0].Mate && b.moves[b.stateNum].evals[0].MateIn == 0 {
+						if status := b.moves[b.stateNum].status; status.IsDecisive() || status == game.Stalemate {
 							return layout.Dimensions{}
 						}
-						return b.bestLines.Layout(gtx, len(b.moves[b.stateNum].evals), func(gtx layout.Context, i int) layout.Dimensions {
+						return b.bestLines.Layout(gtx, len(b.moves[b.stateNum].evals()), func(gtx layout.Context, i int) layout.Dimensions {
 							return b.drawBestLine(gtx, i)
 						})
 					}),
-					// Checkmate notification
+					// Game over notification
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-						if b.bestLines == nil {
-							return layout.Dimensions{}
-						}
 						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
 							layout.Flexed(1, layout.Spacer{}.Layout),
 							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-								if b.moves[b.stateNum].evals[0].Mate && b.moves[b.stateNum].evals[0].MateIn == 0 {
-									label := material.Label(b.gui.theme.giouiTheme, unit.Sp(20), "Checkmate!")
+								if b.moves[b.stateNum].status != game.InProgress {
+									label := material.Label(b.gui.theme.giouiTheme, unit.Sp(20), b.getResultStr(b.stateNum))
 									label.Color = b.gui.theme.text
 									return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 										return label.Layout(gtx)
@@ -126,6 +131,16 @@ func (b *Board) drawAnalysis(gtx layout.Context) layout.Dimensions {
 						}
 						return layout.Spacer{Height: 20}.Layout(gtx)
 					}),
+					// Variations from the current position
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						variations := b.variations()
+						if len(variations) == 0 {
+							return layout.Dimensions{}
+						}
+						return b.variationList.Layout(gtx, len(variations), func(gtx layout.Context, i int) layout.Dimensions {
+							return b.drawVariation(gtx, variations[i])
+						})
+					}),
 					// Move list
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 						if b.activeGameID == 0 {
@@ -193,12 +208,13 @@ func (b *Board) drawEvalGraph(gtx layout.Context) layout.Dimensions {
 				return layout.Dimensions{}
 			}
 			// Get Scores
-			scores := make([]float32, len(b.moves))
-			for i, move := range b.moves {
-				if len(move.evals) == 0 || move.evals[0] == nil {
+			// only the mainline is evaluated, so the graph shows it while a variation is followed
+			scores := make([]float32, len(b.mainline))
+			for i, move := range b.mainline {
+				if evals := move.evals(); len(evals) == 0 || evals[0] == nil {
 					return layout.Dimensions{}
 				}
-				score := b.getScoreMult(i)
+				score := b.scoreMult(move)
 				scores[i] = float32(score) / 1000
 			}
 			xGap := float32(gtx.Constraints.Max.X) / float32(len(scores)-1)
@@ -220,7 +236,7 @@ func (b *Board) drawEvalGraph(gtx layout.Context) layout.Dimensions {
 			outline := clip.Outline{Path: path.End()}.Op()
 			paint.FillShape(gtx.Ops, player1Colour, outline)
 			// Turn indicator
-			centre := centres[b.stateNum]
+			centre := centres[min(b.stateNum, len(scores)-1)]
 			turnIndicator := image.Rectangle{
 				Min: image.Point{
 					X: int(centre.X) - 1,
@@ -249,18 +265,80 @@ func (b *Board) drawEvalGraph(gtx layout.Context) layout.Dimensions {
 	)
 }
 
+// Draw the chances of winning, drawing and losing over the mainline
+//
+// The wins of the player at the bottom of the board rise from the bottom of the
+// graph, the draws are drawn above them and the other player's wins fill the rest
+func (b *Board) drawWDLGraph(gtx layout.Context) layout.Dimensions {
+	if !b.evalComplete() {
+		return layout.Dimensions{}
+	}
+	size := image.Point{
+		X: gtx.Constraints.Max.X,
+		Y: b.squareSize.Y,
+	}
+	th := b.gui.theme
+	player1Colour := th.chessBoardTheme.player1
+	player2Colour := th.chessBoardTheme.player2
+	if b.flipped {
+		player1Colour, player2Colour = player2Colour, player1Colour
+	}
+	wins := make([]int, len(b.mainline))
+	winsOrDraws := make([]int, len(b.mainline))
+	for i, move := range b.mainline {
+		e := eval.GetEvalNum(move.evals(), 1)
+		if e == nil || e.WDL == [3]int{} {
+			label := material.Label(th.giouiTheme, unit.Sp(16), "No win probabilities from the engine")
+			label.Color = th.textMuted
+			return label.Layout(gtx)
+		}
+		win, draw := e.WDL[0], e.WDL[1]
+		if b.flipped {
+			win = e.WDL[2]
+		}
+		wins[i] = win
+		winsOrDraws[i] = win + draw
+	}
+	paint.FillShape(gtx.Ops, player2Colour, clip.Rect{Max: size}.Op())
+	paint.FillShape(gtx.Ops, th.textMuted, areaBelow(gtx, winsOrDraws, size))
+	paint.FillShape(gtx.Ops, player1Colour, areaBelow(gtx, wins, size))
+	// Turn indicator
+	xGap := float32(size.X) / float32(max(1, len(wins)-1))
+	x := int(xGap * float32(min(b.stateNum, len(wins)-1)))
+	turnIndicator := image.Rect(x-1, 0, x+1, size.Y)
+	paint.FillShape(gtx.Ops, th.contrastFg, clip.Rect(turnIndicator).Op())
+	return layout.Dimensions{Size: size}
+}
+
+// Returns the area of the graph below the per mille values of each position
+func areaBelow(gtx layout.Context, values []int, size image.Point) clip.Op {
+	xGap := float32(size.X) / float32(max(1, len(values)-1))
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	path.MoveTo(f32.Pt(0, float32(size.Y)))
+	for i, value := range values {
+		path.LineTo(f32.Pt(xGap*float32(i), float32(size.Y)*(1-float32(value)/1000)))
+	}
+	path.LineTo(f32.Pt(float32(size.X), float32(size.Y)))
+	path.Close()
+	return clip.Outline{Path: path.End()}.Op()
+}
+
 // Draw a segment of the best line
-func (b *Board) drawBestLineSegment(gtx layout.Context, i int, PV int) layout.Dimensions {
+//
+// san is the line drawn, taken once for the whole line as streamed evals can shorten it
+func (b *Board) drawBestLineSegment(gtx layout.Context, i int, PV int, san []string) layout.Dimensions {
 	th := b.gui.theme
-	e := eval.GetEvalNum(b.moves[b.stateNum].evals, PV)
 	var label string
-	bestLine := e.BestLine
+	clickable := &widget.Clickable{}
 	if i == 0 {
 		label = b.getScoreStr(b.stateNum)
 	} else {
-		label = bestLine[i-1]
+		// clicking a move adds the line up to it as a variation
+		label = san[i-1]
+		clickable = b.lineButton(PV, i)
 	}
-	button := material.Button(th.giouiTheme, &widget.Clickable{}, label)
+	button := material.Button(th.giouiTheme, clickable, label)
 	button.CornerRadius = unit.Dp(5)
 	button.Background = th.bg
 	button.Inset = layout.UniformInset(unit.Dp(10))
@@ -279,6 +357,21 @@ func (b *Board) drawBestLineSegment(gtx layout.Context, i int, PV int) layout.Di
 	)
 }
 
+// Draw a button that follows a variation from the current position
+func (b *Board) drawVariation(gtx layout.Context, node *game.Node) layout.Dimensions {
+	move := b.childButton(node)
+	if move == nil {
+		return layout.Dimensions{}
+	}
+	margins := layout.Inset{
+		Right:  5,
+		Bottom: 5,
+	}
+	return margins.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return move.Layout(gtx, b.gui.theme, 0, b.squareSize.X*2-40)
+	})
+}
+
 // Draw a move list element
 func (b *Board) moveListElement(gtx layout.Context, i int) layout.Dimensions {
 	buttonWidth := b.squareSize.X*2 - 40
@@ -345,8 +438,12 @@ func (b *Board) getScoreMult(stateNum int) int {
 	if b.moves == nil {
 		return 500
 	}
-	move := b.moves[stateNum]
-	e := eval.GetEvalNum(move.evals, 1)
+	return b.scoreMult(b.moves[stateNum])
+}
+
+// produce a score multiplier between 100 and 900 for the position after the move
+func (b *Board) scoreMult(move *MoveButton) int {
+	e := eval.GetEvalNum(move.evals(), 1)
 	if e == nil {
 		return 500 // default value
 	}
@@ -369,7 +466,7 @@ func (b *Board) getScoreStr(stateNum int) string {
 		return ""
 	}
 	move := b.moves[stateNum]
-	e := eval.GetEvalNum(move.evals, 1)
+	e := eval.GetEvalNum(move.evals(), 1)
 	if e == nil {
 		return "" // default value
 	}
@@ -377,33 +474,109 @@ func (b *Board) getScoreStr(stateNum int) string {
 		return fmt.Sprintf("M%d", int(math.Abs(float64(e.MateIn))))
 	}
 	score := e.Score
+	lower, upper := e.LowerBound, e.UpperBound
 	if b.flipped {
 		score = -score
+		lower, upper = upper, lower
 	}
-	return fmt.Sprintf("%.1f", float32(score)/100)
+	// the search stopped before the score of a failed search was resolved
+	bound := ""
+	if lower {
+		bound = "≥"
+	} else if upper {
+		bound = "≤"
+	}
+	return fmt.Sprintf("%s%.1f", bound, float32(score)/100)
+}
+
+// Produce a string describing how the game ended e.g. "1-0 checkmate"
+func (b *Board) getResultStr(stateNum int) string {
+	move := b.moves[stateNum]
+	switch {
+	case move.status == game.InProgress:
+		return ""
+	case move.status.IsClaimable():
+		return fmt.Sprintf("Draw can be claimed by %s", move.status)
+	}
+	return fmt.Sprintf("%s %s", move.result, move.status)
 }
 
 // Returns a bool indicating if the game has been evaluated
 func (b *Board) evalComplete() bool {
-	if b.moves == nil {
-		return false
-	}
-	if len(b.moves[len(b.moves)-1].evals) == 0 {
+	if b.mainline == nil {
 		return false
 	}
-	return b.moves[len(b.moves)-1].evals[0] != nil
+	evals := b.mainline[len(b.mainline)-1].evals()
+	return len(evals) > 0 && evals[0] != nil
 }
 
 func (b *Board) drawBestLine(gtx layout.Context, lineNum int) layout.Dimensions {
-	e := eval.GetEvalNum(b.moves[b.stateNum].evals, lineNum+1)
-	return b.BestLineLists[0].Layout(gtx, len(e.BestLine), func(gtx layout.Context, i int) layout.Dimensions {
-		return b.drawBestLineSegment(gtx, i, lineNum+1)
+	e := eval.GetEvalNum(b.moves[b.stateNum].evals(), lineNum+1)
+	if e == nil {
+		return layout.Dimensions{}
+	}
+	san := b.bestLineSAN(e)
+	return b.BestLineLists[0].Layout(gtx, len(san)+1, func(gtx layout.Context, i int) layout.Dimensions {
+		return b.drawBestLineSegment(gtx, i, lineNum+1, san)
 	})
 }
 
+// Returns the best line of the eval in SAN with move numbers
+//
+// The line is played from the current position, so it is converted the first time
+// it is drawn and reused until the eval of its PV is replaced
+func (b *Board) bestLineSAN(e *eval.MoveEval) []string {
+	if line, ok := b.sanLines[e.PVnum]; ok && line.eval == e {
+		return line.san
+	}
+	// a line the engine gives that can't be played is shown up to the bad move
+	san, _ := b.gameState.UCILineToSAN(e.BestLine)
+	if b.sanLines == nil {
+		b.sanLines = map[int]sanLine{}
+	}
+	b.sanLines[e.PVnum] = sanLine{eval: e, san: san}
+	return san
+}
+
 func (b *Board) evalInfo(gtx layout.Context) layout.Dimensions {
-	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
-		layout.Flexed(1, layout.Spacer{}.Layout),
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		// Search information while the position is evaluated
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if status := b.gui.engineStatus(); status != "" {
+				label := material.Label(b.gui.theme.giouiTheme, unit.Sp(16), status)
+				label.Color = b.gui.theme.text
+				label.MaxLines = 2
+				return label.Layout(gtx)
+			}
+			info := b.moves[b.stateNum].searchInfo()
+			if info == nil {
+				return layout.Dimensions{}
+			}
+			label := material.Label(b.gui.theme.giouiTheme, unit.Sp(16), searchInfoStr(info))
+			label.Color = b.gui.theme.textMuted
+			return label.Layout(gtx)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			text := "Analyse"
+			if b.analysing {
+				text = "Stop"
+			}
+			button := material.Button(b.gui.theme.giouiTheme, &b.analyseButton, text)
+			button.TextSize = unit.Sp(14)
+			button.Inset = layout.UniformInset(unit.Dp(5))
+			return button.Layout(gtx)
+		}),
+		layout.Rigid(layout.Spacer{Width: 5}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			text := "Show WDL"
+			if b.showWDL {
+				text = "Hide WDL"
+			}
+			button := material.Button(b.gui.theme.giouiTheme, &b.wdlButton, text)
+			button.TextSize = unit.Sp(14)
+			button.Inset = layout.UniformInset(unit.Dp(5))
+			return button.Layout(gtx)
+		}),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			iButton := material.IconButton(b.gui.theme.giouiTheme, b.refreshButton, b.gui.icons.refreshIcon, "Refresh")
 			iButton.Background = color.NRGBA{0, 0, 0, 0}
@@ -414,3 +587,27 @@ func (b *Board) evalInfo(gtx layout.Context) layout.Dimensions {
 		}),
 	)
 }
+
+// Produce a string describing the progress of a search
+// e.g. "depth 18/24  412k nodes  1.2M nps  W 9.5% D 88.0% L 2.5%"
+func searchInfoStr(info *eval.Info) string {
+	str := fmt.Sprintf("depth %d/%d  %s nodes  %s nps", info.Depth, info.SelDepth, siStr(info.Nodes), siStr(info.NPS))
+	if info.WDL != [3]int{} {
+		str += fmt.Sprintf("  W %.1f%% D %.1f%% L %.1f%%",
+			float32(info.WDL[0])/10, float32(info.WDL[1])/10, float32(info.WDL[2])/10)
+	}
+	return str
+}
+
+// Produce a short string for a large number e.g. 1234567 -> "1.2M"
+func siStr(n int) string {
+	switch {
+	case n >= 1_000_000_000:
+		return fmt.Sprintf("%.1fG", float32(n)/1_000_000_000)
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float32(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%dk", n/1_000)
+	}
+	return fmt.Sprintf("%d", n)
+}
@@ -1,11 +1,13 @@
 package gui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"strings"
+	"sync/atomic"
 
 	"gioui.org/io/key"
 	"gioui.org/layout"
@@ -29,19 +31,53 @@ type Board struct {
 	stateNum      int
 	moves         []*MoveButton
 	flipped       bool
-	evaluated     bool
+	evaluated     atomic.Bool // set by the goroutine evaluating the game once it's done
 	bestLines     *widget.List
 	BestLineLists []*widget.List
 	refreshButton *widget.Clickable
+	sanLines      map[int]sanLine // best lines of the current position converted to SAN, by PV number
+	tree          *game.GameTree
+	mainline      []*MoveButton              // moves of the mainline, which are evaluated
+	nodeButtons   map[*game.Node]*MoveButton // buttons of the moves shown so far, by node
+	variationList *widget.List
+	lineButtons   map[lineSegment]*widget.Clickable
+	input         moveInput
+	analysing     bool               // analyse each position shown until the search is stopped
+	holdsEngine   bool               // engineMu is held by the board's infinite search
+	ctx           context.Context    // cancelled once another game is selected
+	cancel        context.CancelFunc // cancels the board's searches
+	analyseButton widget.Clickable
+	showWDL       bool // draw the chances of winning below the eval graph
+	wdlButton     widget.Clickable
+}
+
+// A move of an engine's best line, the line up to the move can be added as a variation
+type lineSegment struct {
+	pv   int
+	move int
+}
+
+// An engine's best line converted to SAN, kept until the eval it came from is replaced
+type sanLine struct {
+	eval *eval.MoveEval
+	san  []string
 }
 
 type MoveButton struct {
-	move      *game.Move
-	notation  string
-	widget    *widget.Clickable
-	gameState *game.Game
-	evals     []*eval.MoveEval
-	player    string
+	node     *game.Node
+	move     *game.Move
+	notation string
+	widget   *widget.Clickable
+	status   game.Status
+	result   string
+	results  atomic.Pointer[moveResults] // replaced as a whole, as the engine writes it while it's drawn
+	player   string
+}
+
+// The engine's results for a move
+type moveResults struct {
+	evals []*eval.MoveEval
+	info  *eval.Info // latest search information for the first line while it is evaluated
 }
 
 func newBoard(g *GUI, selectedGame *database.Game) *Board {
@@ -58,40 +94,24 @@ func newBoard(g *GUI, selectedGame *database.Game) *Board {
 	if err != nil {
 		return errBoard
 	}
-	// Get the board state for the active game
-	gameState := game.NewGame()
-	// check if the move string is valid
-	err = gameState.Moves(movesFromDB.Moves)
+	// Get the moves with their variations
+	tree, err := g.db.GetGameTree(selectedGame.ID)
 	if err != nil {
 		return errBoard
 	}
-	// turn the move strings into moves
-	gameState.NewGame()
-	moves := make([]*MoveButton, len(movesFromDB.Moves)+1)
-	moves[0] = &MoveButton{
-		move:      nil,
-		notation:  "",
-		widget:    &widget.Clickable{},
-		gameState: gameState.Clone(),
-		player:    "",
-	}
-	for i, moveStr := range movesFromDB.Moves {
-		move, err := gameState.Move(moveStr)
+	// turn the mainline into move buttons
+	gameState := tree.Start()
+	nodeButtons := map[*game.Node]*MoveButton{}
+	moves := []*MoveButton{newMoveButton(gameState, tree.Root)}
+	for _, node := range tree.Mainline() {
+		_, err := gameState.PlayMove(node.Move)
 		if err != nil {
-			break
-		}
-		player := "White"
-		if i%2 == 0 {
-			player = "black"
-		}
-		moves[i+1] = &MoveButton{
-			move:      &move,
-			notation:  moveStr,
-			widget:    &widget.Clickable{},
-			gameState: gameState.Clone(),
-			evals:     []*eval.MoveEval{},
-			player:    player,
+			return errBoard
 		}
+		moves = append(moves, newMoveButton(gameState, node))
+	}
+	for _, move := range moves {
+		nodeButtons[move.node] = move
 	}
 	// check if the board should be flipped
 	flipped := true
@@ -100,16 +120,17 @@ func newBoard(g *GUI, selectedGame *database.Game) *Board {
 	}
 	if movesFromDB.Depth > 0 {
 		// provisionally use scores from the database
-		for i, scoreStr := range movesFromDB.Scores {
+		for i := range movesFromDB.Scores {
 			if i >= len(moves) {
 				break
 			}
-			eval := eval.ParseScoreStr(scoreStr)
-			moves[i].evals = append(moves[i].evals, eval)
+			if e := movesFromDB.EvalAt(i); e != nil {
+				moves[i].setEvals(append(moves[i].evals(), e))
+			}
 		}
 	}
-	// If no engine is loaded, don't proceed to evaluation steps
-	if g.eng == nil {
+	// If no engine is loaded or it can't play the variant, don't proceed to evaluation steps
+	if g.eng == nil || !g.eng.SupportsVariant(selectedGame.Variant.UCIName()) {
 		return &Board{
 			gui:          g,
 			activeGameID: selectedGame.ID,
@@ -124,24 +145,16 @@ func newBoard(g *GUI, selectedGame *database.Game) *Board {
 			moves:         moves,
 			flipped:       flipped,
 			refreshButton: &widget.Clickable{},
+			tree:          tree,
+			mainline:      moves,
+			nodeButtons:   nodeButtons,
+			variationList: &widget.List{
+				List: layout.List{
+					Axis: layout.Horizontal,
+				},
+			},
 		}
 	}
-	// evaluate the game
-	done := make(chan struct{})
-	go evaluateGame(g.eng, gameState.MoveHistory, moves, done)
-	go func() {
-		<-done
-		// Draw a new frame
-		g.board.evaluated = true
-		g.window.Invalidate()
-		// Update the database with the new evals
-		evals := make([][]*eval.MoveEval, len(moves))
-		for i, move := range moves {
-			evals[i] = move.evals
-		}
-		g.db.UpdateEval(movesFromDB.ID, evals)
-	}()
-
 	// create lists for best lines
 	BestLineLists := make([]*widget.List, g.eng.MultiPV)
 	for i := range BestLineLists {
@@ -151,7 +164,8 @@ func newBoard(g *GUI, selectedGame *database.Game) *Board {
 			},
 		}
 	}
-	return &Board{
+	ctx, cancel := context.WithCancel(context.Background())
+	board := &Board{
 		gui:          g,
 		activeGameID: selectedGame.ID,
 		movesList: &widget.List{
@@ -171,6 +185,47 @@ func newBoard(g *GUI, selectedGame *database.Game) *Board {
 		},
 		BestLineLists: BestLineLists,
 		refreshButton: &widget.Clickable{},
+		tree:          tree,
+		mainline:      moves,
+		nodeButtons:   nodeButtons,
+		variationList: &widget.List{
+			List: layout.List{
+				Axis: layout.Horizontal,
+			},
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	// evaluate the game
+	// the move history changes as the board is walked back and forth, so evaluate a copy
+	history := make([]game.Move, len(gameState.MoveHistory))
+	copy(history, gameState.MoveHistory)
+	go func() {
+		// wait for the previous game's searches to stop
+		g.engineMu.Lock()
+		err := evaluateGame(ctx, g.pool, selectedGame.Variant, selectedGame.FEN, history, moves, g.window.Invalidate)
+		g.engineMu.Unlock()
+		if err != nil {
+			return
+		}
+		// Draw a new frame
+		board.evaluated.Store(true)
+		g.window.Invalidate()
+		// Update the database with the new evals
+		evals := make([][]*eval.MoveEval, len(moves))
+		for i, move := range moves {
+			evals[i] = move.evals()
+		}
+		g.db.UpdateEval(movesFromDB.ID, evals)
+	}()
+	return board
+}
+
+// Stops the board's searches so the engine can be used by the next board
+func (b *Board) close() {
+	b.stopAnalysis()
+	if b.cancel != nil {
+		b.cancel()
 	}
 }
 
@@ -221,8 +276,15 @@ func (b *Board) drawBoard(gtx layout.Context) layout.Dimensions {
 	return layout.Flex{Axis: layout.Vertical, Spacing: 0}.Layout(gtx,
 		layout.Rigid(layout.Spacer{Height: 50}.Layout),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-			return layout.Flex{Axis: layout.Vertical, Spacing: 0}.Layout(gtx,
-				b.drawRows()...,
+			return layout.Stack{}.Layout(gtx,
+				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Vertical, Spacing: 0}.Layout(gtx,
+						b.drawRows()...,
+					)
+				}),
+				layout.Expanded(b.moveInputArea),
+				layout.Expanded(b.drawDraggedPiece),
+				layout.Expanded(b.drawPromotionChooser),
 			)
 		}),
 		layout.Rigid(layout.Spacer{Height: 50}.Layout),
@@ -260,6 +322,22 @@ func (b *Board) drawSquare(i, j int) layout.FlexChild {
 				paint.FillShape(gtx.Ops, b.getSquareColour(i, j), clip.Rect(square).Op())
 				return layout.Dimensions{Size: square.Max}
 			}),
+			// Highlight the selected piece and the squares it can move to
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				sq := boardSquare{file: rune('a' + col), rank: row + 1}
+				highlight := b.gui.theme.chessBoardTheme.highlight
+				switch {
+				case b.input.selected != nil && *b.input.selected == sq:
+					paint.FillShape(gtx.Ops, highlight, clip.Rect{Max: b.squareSize}.Op())
+				case b.isTarget(sq):
+					dot := image.Rectangle{
+						Min: b.squareSize.Div(3),
+						Max: b.squareSize.Sub(b.squareSize.Div(3)),
+					}
+					paint.FillShape(gtx.Ops, highlight, clip.Ellipse(dot).Op(gtx.Ops))
+				}
+				return layout.Dimensions{}
+			}),
 			// Draw the file labels
 			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
 				if i != 7 {
@@ -301,6 +379,10 @@ func (b *Board) drawSquare(i, j int) layout.FlexChild {
 				if piece == nil {
 					return layout.Dimensions{}
 				}
+				// the dragged piece is drawn under the pointer instead
+				if b.input.dragging && *b.input.selected == (boardSquare{file: rune('a' + col), rank: row + 1}) {
+					return layout.Dimensions{}
+				}
 				img := b.gui.theme.chessBoardTheme.pieces[piece.GetImageName()]
 				return layout.Flex{Axis: layout.Vertical, Spacing: 0}.Layout(gtx,
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -369,10 +451,38 @@ func (b *Board) updateState(gtx layout.Context) {
 	if b.moves == nil || len(b.moves) == 0 {
 		return
 	}
+	b.updateMoveInput(gtx)
+	if b.wdlButton.Clicked(gtx) {
+		b.showWDL = !b.showWDL
+	}
+	if b.analyseButton.Clicked(gtx) {
+		if b.analysing {
+			b.analysing = false
+			b.stopAnalysis()
+		} else {
+			b.analysing = b.startAnalysis()
+		}
+	}
 	for i, move := range b.moves {
 		if move.widget.Clicked(gtx) {
-			b.gameState = move.gameState
-			b.stateNum = i
+			b.goToState(i)
+		}
+	}
+	for _, node := range b.variations() {
+		if button := b.childButton(node); button != nil && button.widget.Clicked(gtx) {
+			b.followLine(node)
+			return
+		}
+	}
+	for _, e := range b.moves[b.stateNum].evals() {
+		if e == nil {
+			continue
+		}
+		for i := range e.BestLine {
+			if b.lineButton(e.PVnum, i+1).Clicked(gtx) {
+				b.addBestLine(e, i+1)
+				return
+			}
 		}
 	}
 }
@@ -382,37 +492,240 @@ func (b *Board) arrowKeys(e key.Event) {
 	if b.moves == nil {
 		return
 	}
-	var newState *game.Game
 	switch e.Name {
 	case key.NameLeftArrow:
 		if b.stateNum == 0 {
 			return
 		}
-		newState = b.moves[b.stateNum-1].gameState
-		b.stateNum--
+		b.goToState(b.stateNum - 1)
 	case key.NameRightArrow:
 		if b.stateNum == len(b.moves)-1 {
 			return
 		}
-		newState = b.moves[b.stateNum+1].gameState
+		b.goToState(b.stateNum + 1)
+	}
+}
+
+// Walk the game state backwards or forwards to the position after the given move
+//
+// Positions in variations are evaluated when they are first shown,
+// while analysing the search moves on to the new position
+func (b *Board) goToState(stateNum int) {
+	// a piece selected in the previous position can't be moved in the new one
+	b.clearSelection()
+	b.input.promotion = nil
+	b.stopAnalysis()
+	// the best lines of the new position are shown in their place
+	b.sanLines = nil
+	b.lineButtons = nil
+	defer func() {
+		// positions that can't be analysed are still evaluated
+		if !b.analysing || !b.startAnalysis() {
+			b.evaluateCurrent()
+		}
+	}()
+	for b.stateNum > stateNum {
+		_, err := b.gameState.Undo()
+		if err != nil {
+			return
+		}
+		b.stateNum--
+	}
+	for b.stateNum < stateNum {
+		_, err := b.gameState.PlayMove(*b.moves[b.stateNum+1].move)
+		if err != nil {
+			return
+		}
 		b.stateNum++
-	default:
+	}
+}
+
+// Returns the evals of the move's lines
+func (m *MoveButton) evals() []*eval.MoveEval {
+	if results := m.results.Load(); results != nil {
+		return results.evals
+	}
+	return nil
+}
+
+// Returns the latest search information for the first line while the move is evaluated
+func (m *MoveButton) searchInfo() *eval.Info {
+	if results := m.results.Load(); results != nil {
+		return results.info
+	}
+	return nil
+}
+
+// Sets the evals of the move once its search has finished
+func (m *MoveButton) setEvals(evals []*eval.MoveEval) {
+	m.results.Store(&moveResults{evals: evals})
+}
+
+// Replaces the eval of the info's line with it, keeping the evals of the other lines
+//
+// Called from the engine's goroutine while the move is drawn, so the results are
+// copied and swapped rather than modified
+func (m *MoveButton) updateEval(info *eval.Info) {
+	for {
+		old := m.results.Load()
+		var evals []*eval.MoveEval
+		results := &moveResults{}
+		if old != nil {
+			evals = old.evals
+			results.info = old.info
+		}
+		results.evals = make([]*eval.MoveEval, 0, len(evals)+1)
+		replaced := false
+		for _, e := range evals {
+			if e != nil && e.PVnum == info.PVnum {
+				e = info.MoveEval()
+				replaced = true
+			}
+			results.evals = append(results.evals, e)
+		}
+		if !replaced {
+			results.evals = append(results.evals, info.MoveEval())
+		}
+		if info.PVnum == 1 {
+			results.info = info
+		}
+		if m.results.CompareAndSwap(old, results) {
+			return
+		}
+	}
+}
+
+// Creates the button of a move, the game is in the position after the move
+//
+// The root of the tree is the starting position and has no move
+func newMoveButton(g *game.Game, node *game.Node) *MoveButton {
+	button := &MoveButton{
+		node:   node,
+		widget: &widget.Clickable{},
+		status: g.Status(),
+		result: g.Result(),
+	}
+	if node.Parent == nil {
+		return button
+	}
+	move := node.Move
+	button.move = &move
+	button.notation, _ = move.LongAlgebraicNotation()
+	button.setEvals([]*eval.MoveEval{})
+	button.player = "White"
+	if node.Ply()%2 == 1 {
+		button.player = "black"
+	}
+	return button
+}
+
+// Returns the button of a move from the current position, creating it if needed
+func (b *Board) childButton(node *game.Node) *MoveButton {
+	if button, ok := b.nodeButtons[node]; ok {
+		return button
+	}
+	g := b.gameState.Clone()
+	_, err := g.PlayMove(node.Move)
+	if err != nil {
+		return nil
+	}
+	button := newMoveButton(g, node)
+	b.nodeButtons[node] = button
+	return button
+}
+
+// Returns the moves from the current position that aren't next in the move list
+func (b *Board) variations() []*game.Node {
+	if b.tree == nil {
+		return nil
+	}
+	variations := []*game.Node{}
+	for _, child := range b.moves[b.stateNum].node.Children {
+		if b.stateNum+1 < len(b.moves) && b.moves[b.stateNum+1].node == child {
+			continue
+		}
+		variations = append(variations, child)
+	}
+	return variations
+}
+
+// Replaces the moves after the current position with the line leading to the node,
+// continued by the mainline of the variation, and plays the next move
+func (b *Board) followLine(node *game.Node) {
+	moves := make([]*MoveButton, b.stateNum+1)
+	copy(moves, b.moves[:b.stateNum+1])
+	path := node.Path()
+	line := append(path[b.stateNum:], node.Line()[1:]...)
+	g := b.gameState.Clone()
+	for _, n := range line {
+		_, err := g.PlayMove(n.Move)
+		if err != nil {
+			break
+		}
+		button, ok := b.nodeButtons[n]
+		if !ok {
+			button = newMoveButton(g, n)
+			b.nodeButtons[n] = button
+		}
+		moves = append(moves, button)
+	}
+	b.moves = moves
+	b.goToState(b.stateNum + 1)
+}
+
+// Adds the engine's best line up to the given move as a variation from the current
+// position, saves it and follows it
+func (b *Board) addBestLine(e *eval.MoveEval, n int) {
+	current := b.moves[b.stateNum].node
+	moves, _, err := b.gameState.UCILine(e.BestLine[:n])
+	if err != nil {
 		return
 	}
-	b.gameState = newState
+	node, err := b.tree.AddLine(current, moves)
+	if err != nil {
+		return
+	}
+	b.gui.db.SaveGameTree(b.activeGameID, b.tree)
+	b.followLine(node)
 }
 
-// Get the engine to evaluate the game
-func evaluateGame(engine *eval.Engine, moves []game.Move, moveButtons []*MoveButton, done chan struct{}) error {
-	if engine == nil {
+// Returns the button of a move of an engine's best line
+func (b *Board) lineButton(pv int, move int) *widget.Clickable {
+	segment := lineSegment{pv: pv, move: move}
+	if b.lineButtons == nil {
+		b.lineButtons = map[lineSegment]*widget.Clickable{}
+	}
+	if _, ok := b.lineButtons[segment]; !ok {
+		b.lineButtons[segment] = &widget.Clickable{}
+	}
+	return b.lineButtons[segment]
+}
+
+// Get the engines to evaluate the game
+//
+// The evals of each move are updated as the engines search deeper, calling
+// invalidate to draw a new frame. The moves are left as they are once the context is cancelled
+func evaluateGame(ctx context.Context, pool *eval.Pool, variant game.Variant, fen string, moves []game.Move, moveButtons []*MoveButton, invalidate func()) error {
+	if pool == nil {
 		return errors.New("no engine")
 	}
+	err := pool.SetVariant(variant.UCIName())
+	if err != nil {
+		return err
+	}
 	notations := game.ConvertMovesToUCINotation(moves)
-	evalss := engine.EvalGame(strings.Join(notations, " "))
+	evalss, err := pool.EvalGameStream(ctx, fen, strings.Join(notations, " "), func(ply int, info *eval.Info) {
+		if ctx.Err() != nil {
+			return
+		}
+		moveButtons[ply].updateEval(info)
+		invalidate()
+	})
+	if err != nil {
+		return err
+	}
 	for i, evals := range evalss {
-		moveButtons[i].evals = evals
+		moveButtons[i].setEvals(evals)
 	}
-	// signal that the evaluation is done
-	done <- struct{}{}
 	return nil
 }
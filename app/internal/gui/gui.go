@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 
 	"gioui.org/app"
 	"gioui.org/layout"
@@ -21,6 +22,7 @@ import (
 	"gioui.org/x/component"
 	"github.com/LoreviQ/ChessAnalysis/app/internal/database"
 	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
+	"github.com/ncruces/zenity"
 	"golang.org/x/exp/shiny/materialdesign/icons"
 )
 
@@ -40,7 +42,10 @@ type GUI struct {
 	db *database.Database
 
 	// Engine
-	eng *eval.Engine
+	pool     *eval.Pool   // engines sharing the evaluation of games
+	eng      *eval.Engine // the pool's first engine, used to evaluate single positions
+	engineMu sync.Mutex   // held while the board is searching, so it queues one search at a time
+	poolErr  error        // why the engines couldn't be started
 }
 
 type chessAnalysisTheme struct {
@@ -56,11 +61,12 @@ type chessAnalysisTheme struct {
 }
 
 type chessBoardTheme struct {
-	square1 color.NRGBA
-	square2 color.NRGBA
-	player1 color.NRGBA
-	player2 color.NRGBA
-	pieces  map[string]*image.Image
+	square1   color.NRGBA
+	square2   color.NRGBA
+	player1   color.NRGBA
+	player2   color.NRGBA
+	highlight color.NRGBA // selected piece and the squares it can move to
+	pieces    map[string]*image.Image
 }
 
 type myIcons struct {
@@ -68,13 +74,15 @@ type myIcons struct {
 }
 
 type config struct {
-	EnginePath string `json:"EnginePath"`
-	SyzygyPath string `json:"SyzygyPath"`
-	Movetime   int    `json:"Movetime"`
-	Depth      int    `json:"Depth"`
-	Threads    int    `json:"Threads"`
-	Hash       int    `json:"Hash"`
-	MultiPV    int    `json:"MultiPV"`
+	EnginePath string            `json:"EnginePath"`
+	SyzygyPath string            `json:"SyzygyPath"`
+	Movetime   int               `json:"Movetime"`
+	Depth      int               `json:"Depth"`
+	Threads    int               `json:"Threads"`
+	Hash       int               `json:"Hash"`
+	MultiPV    int               `json:"MultiPV"`
+	Engines    int               `json:"Engines"` // number of engine processes evaluating games
+	Options    map[string]string `json:"Options"` // the engine's other options, by name
 }
 
 func NewTheme(theme string) *chessAnalysisTheme {
@@ -129,11 +137,12 @@ func NewChessBoardTheme(theme string) *chessBoardTheme {
 			}
 		}
 		return &chessBoardTheme{
-			square1: color.NRGBA{235, 218, 183, 255},
-			square2: color.NRGBA{172, 138, 102, 255},
-			player1: color.NRGBA{255, 255, 255, 255},
-			player2: color.NRGBA{0, 0, 0, 255},
-			pieces:  imageMap,
+			square1:   color.NRGBA{235, 218, 183, 255},
+			square2:   color.NRGBA{172, 138, 102, 255},
+			player1:   color.NRGBA{255, 255, 255, 255},
+			player2:   color.NRGBA{0, 0, 0, 255},
+			highlight: color.NRGBA{20, 85, 30, 128},
+			pieces:    imageMap,
 		}
 	case "HotDogStand":
 		imageMap, err := loadImages(theme)
@@ -144,11 +153,12 @@ func NewChessBoardTheme(theme string) *chessBoardTheme {
 			}
 		}
 		return &chessBoardTheme{
-			square1: color.NRGBA{255, 0, 0, 255},
-			square2: color.NRGBA{255, 255, 0, 255},
-			player1: color.NRGBA{255, 0, 0, 255},
-			player2: color.NRGBA{255, 255, 0, 255},
-			pieces:  imageMap,
+			square1:   color.NRGBA{255, 0, 0, 255},
+			square2:   color.NRGBA{255, 255, 0, 255},
+			player1:   color.NRGBA{255, 0, 0, 255},
+			player2:   color.NRGBA{255, 255, 0, 255},
+			highlight: color.NRGBA{0, 0, 255, 128},
+			pieces:    imageMap,
 		}
 	default: // chess.com theme
 		theme = "chess.com"
@@ -157,11 +167,12 @@ func NewChessBoardTheme(theme string) *chessBoardTheme {
 			return nil
 		}
 		return &chessBoardTheme{
-			square1: color.NRGBA{234, 236, 206, 255},
-			square2: color.NRGBA{114, 148, 82, 255},
-			player1: color.NRGBA{255, 255, 255, 255},
-			player2: color.NRGBA{64, 61, 57, 255},
-			pieces:  imageMap,
+			square1:   color.NRGBA{234, 236, 206, 255},
+			square2:   color.NRGBA{114, 148, 82, 255},
+			player1:   color.NRGBA{255, 255, 255, 255},
+			player2:   color.NRGBA{64, 61, 57, 255},
+			highlight: color.NRGBA{255, 255, 51, 128},
+			pieces:    imageMap,
 		}
 	}
 }
@@ -193,6 +204,7 @@ func NewGUI(width, height int, db *database.Database) *GUI {
 		Threads:    4,
 		Hash:       128,
 		MultiPV:    1,
+		Engines:    1,
 	}
 	settings, err := loadConfig()
 	if err != nil {
@@ -208,7 +220,8 @@ func NewGUI(width, height int, db *database.Database) *GUI {
 		icons:  icons,
 		db:     db,
 	}
-	g.eng, _ = eval.InitializeStockfish(settings.EnginePath, settings.SyzygyPath, settings.Movetime, settings.Depth, settings.Threads, settings.Hash, settings.MultiPV)
+	g.setPool(eval.InitializeEnginePool(settings.Engines, settings.EnginePath, settings.SyzygyPath, settings.Movetime, settings.Depth, settings.Threads, settings.Hash, settings.MultiPV))
+	g.setOptions(settings.Options)
 	g.header = newHeader(g)
 	g.board = newBoard(g, nil)
 	g.sidebar = newSidebar(g)
@@ -217,6 +230,49 @@ func NewGUI(width, height int, db *database.Database) *GUI {
 	return g
 }
 
+// Replaces the engines, leaving them unloaded if the pool couldn't be started
+func (g *GUI) setPool(pool *eval.Pool, err error) {
+	g.poolErr = err
+	if err != nil {
+		return
+	}
+	if g.pool != nil {
+		g.pool.Close()
+	}
+	g.pool = pool
+	g.eng = pool.Engines[0]
+	// show the engines restarting as it happens
+	pool.OnStatus(g.window.Invalidate)
+}
+
+// Describes the engines if they aren't running, or returns an empty string
+func (g *GUI) engineStatus() string {
+	if g.pool == nil {
+		if g.poolErr != nil {
+			return fmt.Sprintf("No engine loaded: %v", g.poolErr)
+		}
+		return "No engine loaded"
+	}
+	status, err := g.pool.Status()
+	switch status {
+	case eval.StatusRestarting:
+		return fmt.Sprintf("Engine restarting after: %v", err)
+	case eval.StatusFailed:
+		return fmt.Sprintf("Engine failed to restart: %v", err)
+	}
+	return ""
+}
+
+// Sets the options of the engines that advertise them
+func (g *GUI) setOptions(options map[string]string) {
+	if g.pool == nil {
+		return
+	}
+	for name, value := range options {
+		g.pool.ChangeOption(name, value)
+	}
+}
+
 // CreateGUI creates the GUI
 func (g *GUI) CreateGUI() {
 	go g.draw()
@@ -288,6 +344,63 @@ func (g *GUI) Layout(gtx layout.Context) layout.Dimensions {
 	)
 }
 
+// Prompts for a PGN file and imports its games into the database
+//
+// The games are stored from the side of the player asked for, found in their
+// White and Black tags, and from White's side if none is given
+func (g *GUI) importPGN() {
+	filePath, err := zenity.SelectFile(zenity.FileFilter{
+		Name:     "PGN files",
+		Patterns: []string{"*.pgn"},
+	})
+	if err != nil || filePath == "" {
+		return
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		zenity.Error(fmt.Sprintf("Failed to read %s: %v", filePath, err))
+		return
+	}
+	player, err := zenity.Entry("Your name in the games' White and Black tags, leave empty to import them from White's side", zenity.Title("Import PGN"))
+	if err != nil {
+		return
+	}
+	_, err = g.db.InsertPGN(string(data), strings.TrimSpace(player))
+	if err != nil {
+		zenity.Error(fmt.Sprintf("Failed to import %s: %v", filePath, err))
+		return
+	}
+	g.window.Invalidate()
+}
+
+// Opens a file dialog and saves the game with the given id, annotated with its evals
+func (g *GUI) exportPGN(gameID int) {
+	if gameID == 0 {
+		zenity.Error("Select a game to export")
+		return
+	}
+	pgn, err := g.db.ExportPGN(gameID)
+	if err != nil {
+		zenity.Error(fmt.Sprintf("Failed to export game: %v", err))
+		return
+	}
+	filePath, err := zenity.SelectFileSave(
+		zenity.Filename(fmt.Sprintf("game_%d.pgn", gameID)),
+		zenity.ConfirmOverwrite(),
+		zenity.FileFilter{
+			Name:     "PGN files",
+			Patterns: []string{"*.pgn"},
+		},
+	)
+	if err != nil || filePath == "" {
+		return
+	}
+	err = os.WriteFile(filePath, []byte(pgn), 0644)
+	if err != nil {
+		zenity.Error(fmt.Sprintf("Failed to write %s: %v", filePath, err))
+	}
+}
+
 func loadImages(themeName string) (map[string]*image.Image, error) {
 	pieces := make(map[string]*image.Image)
 	dir := filepath.Join("assets", "images", themeName)
@@ -373,7 +486,7 @@ func setDefaults(settings, defaultSettings *config) *config {
 }
 
 func isZeroValue(v reflect.Value) bool {
-	return v.Interface() == reflect.Zero(v.Type()).Interface()
+	return v.IsZero()
 }
 
 func loadIcons() *myIcons {
@@ -25,6 +25,7 @@ type headerButton struct {
 	menu       *component.MenuState
 	subButtons []*headerDropDownButton
 	show       bool
+	onClick    func() // called on click instead of toggling show
 }
 
 type headerDropDownButton struct {
@@ -35,7 +36,7 @@ type headerDropDownButton struct {
 
 func newHeader(g *GUI) *header {
 	// Themes header button
-	buttons := make([]*headerButton, 2)
+	buttons := make([]*headerButton, 4)
 	themes := []string{"chess.com", "lichess.org", "HotDogStand"}
 	subButtons := make([]*headerDropDownButton, len(themes))
 	for i, theme := range themes {
@@ -63,6 +64,28 @@ func newHeader(g *GUI) *header {
 		subButtons: nil,
 		show:       false,
 	}
+	// Import PGN header button
+	buttons[2] = &headerButton{
+		name:       "Import PGN",
+		widget:     &widget.Clickable{},
+		menu:       &component.MenuState{},
+		subButtons: nil,
+		show:       false,
+		onClick: func() {
+			go g.importPGN()
+		},
+	}
+	// Export PGN header button
+	buttons[3] = &headerButton{
+		name:       "Export PGN",
+		widget:     &widget.Clickable{},
+		menu:       &component.MenuState{},
+		subButtons: nil,
+		show:       false,
+		onClick: func() {
+			go g.exportPGN(g.board.activeGameID)
+		},
+	}
 
 	// Add more buttons here
 	return &header{
@@ -162,6 +185,10 @@ func (h *header) updateState(gtx layout.Context) {
 	// Header button click
 	for _, headerButton := range h.buttons {
 		if headerButton.widget.Clicked(gtx) {
+			if headerButton.onClick != nil {
+				headerButton.onClick()
+				continue
+			}
 			headerButton.show = !headerButton.show
 		}
 	}
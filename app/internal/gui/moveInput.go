@@ -0,0 +1,334 @@
+package gui
+
+import (
+	"image"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"github.com/LoreviQ/ChessAnalysis/app/internal/eval"
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
+)
+
+// The pieces a pawn can promote to, in the order they are offered
+var promotionPieces = []game.PieceType{game.Queen, game.Rook, game.Bishop, game.Knight}
+
+// Moves entered on the board by clicking or dragging pieces
+type moveInput struct {
+	selected         *boardSquare // square of the piece being moved, nil if none
+	moves            []game.Move  // legal moves of the selected piece
+	dragging         bool
+	dragPos          f32.Point   // pointer position relative to the top left of the board
+	promotion        []game.Move // promotions waiting for a piece to be chosen, in the order of promotionPieces
+	promotionButtons [4]widget.Clickable
+}
+
+type boardSquare struct {
+	file rune
+	rank int
+}
+
+// Handles clicks and drags on the board and the promotion chooser
+func (b *Board) updateMoveInput(gtx layout.Context) {
+	if b.tree == nil {
+		return
+	}
+	input := &b.input
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: input,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch e.Kind {
+		case pointer.Press:
+			// pressing away from the promotion chooser cancels the move
+			if len(input.promotion) > 0 {
+				input.promotion = nil
+				b.clearSelection()
+				continue
+			}
+			sq, ok := b.squareAt(e.Position)
+			if !ok {
+				continue
+			}
+			if input.selected != nil && b.enterMove(sq) {
+				continue
+			}
+			b.selectSquare(sq)
+			if input.selected != nil {
+				input.dragging = true
+				input.dragPos = e.Position
+			}
+		case pointer.Drag:
+			if input.dragging {
+				input.dragPos = e.Position
+			}
+		case pointer.Release:
+			if !input.dragging {
+				continue
+			}
+			input.dragging = false
+			sq, ok := b.squareAt(e.Position)
+			// releasing on the piece's own square leaves it selected to be moved by clicking
+			if !ok || sq == *input.selected {
+				continue
+			}
+			if !b.enterMove(sq) {
+				b.clearSelection()
+			}
+		case pointer.Cancel:
+			input.dragging = false
+		}
+	}
+	for i := range input.promotionButtons {
+		if input.promotionButtons[i].Clicked(gtx) && i < len(input.promotion) {
+			b.playInputMove(input.promotion[i])
+		}
+	}
+}
+
+// Returns the square under a position relative to the top left of the board
+func (b *Board) squareAt(pos f32.Point) (boardSquare, bool) {
+	if b.squareSize.X == 0 || pos.X < 0 || pos.Y < 0 {
+		return boardSquare{}, false
+	}
+	i := int(pos.Y) / b.squareSize.Y
+	j := int(pos.X) / b.squareSize.X
+	if i > 7 || j > 7 {
+		return boardSquare{}, false
+	}
+	row, col := 7-i, j
+	if b.flipped {
+		row, col = i, 7-j
+	}
+	return boardSquare{file: rune('a' + col), rank: row + 1}, true
+}
+
+// Selects the piece on the square if it belongs to the side to move, otherwise clears the selection
+func (b *Board) selectSquare(sq boardSquare) {
+	moves := b.gameState.MovesFrom(sq.file, sq.rank)
+	if len(moves) == 0 {
+		b.clearSelection()
+		return
+	}
+	b.input.selected = &sq
+	b.input.moves = moves
+}
+
+func (b *Board) clearSelection() {
+	b.input.selected = nil
+	b.input.moves = nil
+	b.input.dragging = false
+}
+
+// Returns true if the selected piece can move to the square
+func (b *Board) isTarget(sq boardSquare) bool {
+	for _, move := range b.input.moves {
+		if file, rank := move.Destination(); file == sq.file && rank == sq.rank {
+			return true
+		}
+	}
+	return false
+}
+
+// Moves the selected piece to the square, asking for the piece to promote to if needed
+//
+// Returns false if the piece can't move to the square
+func (b *Board) enterMove(sq boardSquare) bool {
+	candidates := []game.Move{}
+	// moving the king onto its own rook castles, but other moves to the
+	// square take precedence as in Chess960 the king may move there normally
+	for _, move := range b.input.moves {
+		if move.ToFile == sq.file && move.ToRank == sq.rank {
+			candidates = append(candidates, move)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, move := range b.input.moves {
+			if file, rank := move.Destination(); file == sq.file && rank == sq.rank {
+				candidates = append(candidates, move)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	if candidates[0].Promotion == 0 {
+		b.playInputMove(candidates[0])
+		return true
+	}
+	b.input.promotion = make([]game.Move, len(promotionPieces))
+	for _, move := range candidates {
+		i := strings.IndexRune("QRBN", move.Promotion)
+		if i >= 0 {
+			b.input.promotion[i] = move
+		}
+	}
+	b.input.dragging = false
+	return true
+}
+
+// Adds the move as a variation from the current position, saves it and follows it
+func (b *Board) playInputMove(move game.Move) {
+	b.input.promotion = nil
+	b.clearSelection()
+	current := b.moves[b.stateNum].node
+	node, err := b.tree.AddMove(current, move)
+	if err != nil {
+		return
+	}
+	b.gui.db.SaveGameTree(b.activeGameID, b.tree)
+	b.followLine(node)
+}
+
+// Evaluates the current position in the background if it hasn't been evaluated
+//
+// The engine is only used once the game has been evaluated and while no other
+// position is being evaluated
+func (b *Board) evaluateCurrent() {
+	eng := b.gui.eng
+	button := b.moves[b.stateNum]
+	if eng == nil || !b.evaluated.Load() || b.holdsEngine || len(button.evals()) > 0 {
+		return
+	}
+	if button.status.IsDecisive() || button.status == game.Stalemate {
+		return
+	}
+	if !b.gui.engineMu.TryLock() {
+		return
+	}
+	fen := b.gameState.FEN()
+	go func() {
+		defer b.gui.engineMu.Unlock()
+		evals, err := eng.EvalFENStream(b.ctx, fen, func(info *eval.Info) {
+			if b.ctx.Err() != nil {
+				return
+			}
+			button.updateEval(info)
+			b.gui.window.Invalidate()
+		})
+		if err != nil {
+			return
+		}
+		button.setEvals(evals)
+		b.gui.window.Invalidate()
+	}()
+}
+
+// Starts an infinite search of the current position, streaming its results to the move
+//
+// As with evaluateCurrent the engine is only used once the game has been evaluated.
+// Returns false if the search wasn't started
+func (b *Board) startAnalysis() bool {
+	eng := b.gui.eng
+	button := b.moves[b.stateNum]
+	if eng == nil || !b.evaluated.Load() {
+		return false
+	}
+	if button.status.IsDecisive() || button.status == game.Stalemate {
+		return false
+	}
+	if !b.gui.engineMu.TryLock() {
+		return false
+	}
+	err := eng.StartInfinite(b.ctx, b.gameState.FEN(), "", func(info *eval.Info) {
+		button.updateEval(info)
+		b.gui.window.Invalidate()
+	})
+	if err != nil {
+		b.gui.engineMu.Unlock()
+		return false
+	}
+	b.holdsEngine = true
+	return true
+}
+
+// Stops the infinite search, keeping its results as the evals of the move it analysed
+//
+// engineMu is released even if the search already ended, as it does when the board's
+// context is cancelled or the engine is replaced
+func (b *Board) stopAnalysis() {
+	if !b.holdsEngine {
+		return
+	}
+	b.holdsEngine = false
+	defer b.gui.engineMu.Unlock()
+	eng := b.gui.eng
+	if eng == nil || !eng.Searching() {
+		return
+	}
+	button := b.moves[b.stateNum]
+	evals, err := eng.Stop()
+	if err != nil || len(evals) == 0 {
+		evals = button.evals()
+	}
+	button.setEvals(evals)
+}
+
+// Registers the board as the target of pointer events
+func (b *Board) moveInputArea(gtx layout.Context) layout.Dimensions {
+	size := gtx.Constraints.Min
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, &b.input)
+	return layout.Dimensions{Size: size}
+}
+
+// Draws the piece being dragged under the pointer
+func (b *Board) drawDraggedPiece(gtx layout.Context) layout.Dimensions {
+	if !b.input.dragging || b.input.selected == nil {
+		return layout.Dimensions{}
+	}
+	sq := b.input.selected
+	piece := b.gameState.Board.Squares[sq.rank-1][sq.file-'a']
+	if piece == nil {
+		return layout.Dimensions{}
+	}
+	img := b.gui.theme.chessBoardTheme.pieces[piece.GetImageName()]
+	offset := image.Point{
+		X: int(b.input.dragPos.X) - b.squareSize.X/2,
+		Y: int(b.input.dragPos.Y) - b.squareSize.Y/2,
+	}
+	defer op.Offset(offset).Push(gtx.Ops).Pop()
+	b.drawImage(*img)(gtx)
+	return layout.Dimensions{}
+}
+
+// Draws the pieces a pawn can promote to while the promotion is being chosen
+func (b *Board) drawPromotionChooser(gtx layout.Context) layout.Dimensions {
+	if len(b.input.promotion) == 0 {
+		return layout.Dimensions{}
+	}
+	th := b.gui.theme
+	children := make([]layout.FlexChild, len(promotionPieces))
+	for i, pieceType := range promotionPieces {
+		piece := &game.Piece{Color: b.gameState.Turn, PieceType: pieceType}
+		img := th.chessBoardTheme.pieces[piece.GetImageName()]
+		children[i] = layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return b.input.promotionButtons[i].Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				square := image.Rectangle{Max: b.squareSize}
+				paint.FillShape(gtx.Ops, th.bg, clip.Rect(square).Op())
+				b.drawImage(*img)(gtx)
+				return layout.Dimensions{Size: square.Max}
+			})
+		})
+	}
+	return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+		})
+	})
+}
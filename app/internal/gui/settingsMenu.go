@@ -3,6 +3,7 @@ package gui
 import (
 	"fmt"
 	"image"
+	"slices"
 	"strconv"
 
 	"gioui.org/layout"
@@ -19,16 +20,21 @@ type settingsMenu struct {
 	gui          *GUI
 	settings     []*setting
 	submitButton *widget.Clickable
+	list         *widget.List
 }
 
 type setting struct {
 	name        string
-	settingType string
+	settingType string // "button" to choose a file, "editor", "toggle" between values or an "action"
 	editor      *widget.Editor
 	button      *widget.Clickable
 	data        string
+	option      *eval.Option // the engine's option, nil for the app's settings
 }
 
+// Options set by the analysis rather than the user
+var hiddenOptions = []string{"UCI_Variant", "UCI_Chess960", "UCI_ShowWDL"}
+
 func newSettingsMenu(g *GUI) *settingsMenu {
 	settings := []*setting{
 		{
@@ -43,18 +49,6 @@ func newSettingsMenu(g *GUI) *settingsMenu {
 				return g.eng.Path
 			}(),
 		},
-		{
-			name:        "SyzygyPath",
-			settingType: "button",
-			editor:      nil,
-			button:      &widget.Clickable{},
-			data: func() string {
-				if g.eng == nil {
-					return ""
-				}
-				return g.eng.SyzygyPath
-			}(),
-		},
 		{
 			name:        "Movetime",
 			settingType: "editor",
@@ -80,47 +74,73 @@ func newSettingsMenu(g *GUI) *settingsMenu {
 			}(),
 		},
 		{
-			name:        "Threads",
+			name:        "Engines",
 			settingType: "editor",
 			editor:      &widget.Editor{},
 			button:      nil,
 			data: func() string {
-				if g.eng == nil {
+				if g.pool == nil {
 					return ""
 				}
-				return fmt.Sprintf("%d", g.eng.Threads)
-			}(),
-		},
-		{
-			name:        "Hash",
-			settingType: "editor",
-			editor:      &widget.Editor{},
-			button:      nil,
-			data: func() string {
-				if g.eng == nil {
-					return ""
-				}
-				return fmt.Sprintf("%d", g.eng.Hash)
-			}(),
-		},
-		{
-			name:        "MultiPV",
-			settingType: "editor",
-			editor:      &widget.Editor{},
-			button:      nil,
-			data: func() string {
-				if g.eng == nil {
-					return ""
-				}
-				return fmt.Sprintf("%d", g.eng.MultiPV)
+				return fmt.Sprintf("%d", len(g.pool.Engines))
 			}(),
 		},
 	}
+	if g.eng != nil {
+		for _, option := range g.eng.Options {
+			if !slices.Contains(hiddenOptions, option.Name) {
+				settings = append(settings, newOptionSetting(g, option))
+			}
+		}
+	}
 	return &settingsMenu{
 		gui:          g,
 		settings:     settings,
 		submitButton: &widget.Clickable{},
+		list: &widget.List{
+			List: layout.List{
+				Axis: layout.Vertical,
+			},
+		},
+	}
+}
+
+// Creates the control for an option advertised by the engine
+func newOptionSetting(g *GUI, option *eval.Option) *setting {
+	s := &setting{
+		name:   option.Name,
+		option: option,
+		data:   option.Value,
+	}
+	switch option.Type {
+	case eval.OptionCheck, eval.OptionCombo:
+		s.settingType = "toggle"
+		s.button = &widget.Clickable{}
+	case eval.OptionButton:
+		s.settingType = "action"
+		s.button = &widget.Clickable{}
+	case eval.OptionString:
+		s.settingType = "editor"
+		s.editor = &widget.Editor{SingleLine: true}
+		if s.data == "<empty>" {
+			s.data = ""
+		}
+	default:
+		s.settingType = "editor"
+		s.editor = &widget.Editor{SingleLine: true}
+	}
+	switch option.Name {
+	case "SyzygyPath":
+		s.settingType = "button"
+		s.editor = nil
+		s.button = &widget.Clickable{}
+	// the engines each have a share of the threads and hash table
+	case "Threads":
+		s.data = fmt.Sprintf("%d", g.pool.Threads)
+	case "Hash":
+		s.data = fmt.Sprintf("%d", g.pool.Hash)
 	}
+	return s
 }
 
 func (sm *settingsMenu) Layout(gtx layout.Context) layout.Dimensions {
@@ -165,22 +185,23 @@ func (sm *settingsMenu) Menu(gtx layout.Context) layout.Dimensions {
 	// labels
 	title := material.Label(sm.gui.theme.giouiTheme, unit.Sp(32), "Settings")
 	title.Color = sm.gui.theme.text
-	children := make([]layout.FlexChild, len(sm.settings)+1)
-	children[0] = layout.Rigid(title.Layout)
-	for i, setting := range sm.settings {
-		children[i+1] = setting.Layout(gtx, sm.gui.theme)
-	}
 	return layout.Stack{}.Layout(gtx,
 		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
 			// Maintain the existing dimensions
 			return layout.Dimensions{Size: bounds}
 		}),
 		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			// leave room for the submit button
+			gtx.Constraints.Max = bounds.Sub(image.Pt(0, 100))
 			return margins.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-					// Layout the settings
-					children...,
-				)
+				// Layout the settings, which scroll as engines may have many options
+				list := material.List(sm.gui.theme.giouiTheme, sm.list)
+				return list.Layout(gtx, len(sm.settings)+1, func(gtx layout.Context, i int) layout.Dimensions {
+					if i == 0 {
+						return title.Layout(gtx)
+					}
+					return sm.settings[i-1].Layout(gtx, sm.gui.theme)
+				})
 			})
 		}),
 		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
@@ -197,49 +218,54 @@ func (sm *settingsMenu) Menu(gtx layout.Context) layout.Dimensions {
 	)
 }
 
-func (s *setting) Layout(gtx layout.Context, th *chessAnalysisTheme) layout.FlexChild {
-	return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-		name := material.Label(th.giouiTheme, unit.Sp(16), s.name)
-		name.Color = th.text
-		margin := layout.Inset{
-			Top: unit.Dp(30),
-		}
-		return margin.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-			return layout.Stack{}.Layout(gtx,
-				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
-					offset := layout.Inset{
-						Top: unit.Dp(7),
-					}
-					if s.settingType == "editor" {
-						offset.Top = unit.Dp(0)
+func (s *setting) Layout(gtx layout.Context, th *chessAnalysisTheme) layout.Dimensions {
+	name := material.Label(th.giouiTheme, unit.Sp(16), s.name)
+	name.Color = th.text
+	margin := layout.Inset{
+		Top: unit.Dp(30),
+	}
+	return margin.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Stack{}.Layout(gtx,
+			layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+				offset := layout.Inset{
+					Top: unit.Dp(7),
+				}
+				if s.settingType == "editor" {
+					offset.Top = unit.Dp(0)
+				}
+				return offset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return name.Layout(gtx)
+				})
+			}),
+			layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+				offset := layout.Inset{
+					Left: unit.Dp(200),
+				}
+				switch s.settingType {
+				case "button", "toggle", "action":
+					text := s.data
+					if s.settingType == "action" {
+						text = "Press"
+						if s.data != "" {
+							text = "Pressed on submit"
+						}
 					}
+					button := material.Button(th.giouiTheme, s.button, text)
+					button.Background = th.bg
 					return offset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-						return name.Layout(gtx)
+						return button.Layout(gtx)
 					})
-				}),
-				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
-					offset := layout.Inset{
-						Left: unit.Dp(200),
-					}
-					switch s.settingType {
-					case "button":
-						button := material.Button(th.giouiTheme, s.button, s.data)
-						button.Background = th.bg
-						return offset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-							return button.Layout(gtx)
-						})
-					case "editor":
-						editor := material.Editor(th.giouiTheme, s.editor, s.data)
-						editor.Color = th.text
-						editor.HintColor = th.text
-						return offset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-							return editor.Layout(gtx)
-						})
-					}
-					return layout.Dimensions{}
-				}),
-			)
-		})
+				case "editor":
+					editor := material.Editor(th.giouiTheme, s.editor, s.data)
+					editor.Color = th.text
+					editor.HintColor = th.text
+					return offset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return editor.Layout(gtx)
+					})
+				}
+				return layout.Dimensions{}
+			}),
+		)
 	})
 }
 
@@ -267,6 +293,18 @@ func (sm *settingsMenu) updateState(gtx layout.Context) {
 					}
 				}
 			}
+		case "toggle":
+			if setting.button.Clicked(gtx) {
+				setting.data = nextValue(setting.option, setting.data)
+			}
+		case "action":
+			if setting.button.Clicked(gtx) {
+				if setting.data == "" {
+					setting.data = "pressed"
+				} else {
+					setting.data = ""
+				}
+			}
 		}
 	}
 	if sm.submitButton.Clicked(gtx) {
@@ -276,6 +314,30 @@ func (sm *settingsMenu) updateState(gtx layout.Context) {
 
 }
 
+// Returns the value after the current one of a check or combo option
+func nextValue(option *eval.Option, current string) string {
+	if option.Type == eval.OptionCheck {
+		if current == "true" {
+			return "false"
+		}
+		return "true"
+	}
+	if len(option.Vars) == 0 {
+		return current
+	}
+	i := slices.Index(option.Vars, current)
+	return option.Vars[(i+1)%len(option.Vars)]
+}
+
+// Returns the integer value of a setting, or the fallback if the engine doesn't have it
+func intSetting(settings map[string]string, name string, fallback int) (int, error) {
+	value, ok := settings[name]
+	if !ok {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
 // submitSettings saves the settings to the config file and updates the engine
 func (sm *settingsMenu) submitSettings() error {
 	settings := map[string]string{}
@@ -288,11 +350,11 @@ func (sm *settingsMenu) submitSettings() error {
 			} else {
 				settings[setting.name] = setting.data
 			}
-		case "button":
+		case "button", "toggle", "action":
 			settings[setting.name] = setting.data
 		}
 	}
-	var moveTime, depth, threads, hash, multiPV int
+	var moveTime, depth, threads, hash, multiPV, engines int
 	var err error
 	moveTime, err = strconv.Atoi(settings["Movetime"])
 	if err != nil {
@@ -302,23 +364,35 @@ func (sm *settingsMenu) submitSettings() error {
 	if err != nil {
 		return err
 	}
-	threads, err = strconv.Atoi(settings["Threads"])
+	engines, err = strconv.Atoi(settings["Engines"])
 	if err != nil {
 		return err
 	}
-	hash, err = strconv.Atoi(settings["Hash"])
+	threads, err = intSetting(settings, "Threads", 1)
 	if err != nil {
 		return err
 	}
-	multiPV, err = strconv.Atoi(settings["MultiPV"])
+	hash, err = intSetting(settings, "Hash", 16)
 	if err != nil {
 		return err
 	}
+	multiPV, err = intSetting(settings, "MultiPV", 1)
+	if err != nil {
+		return err
+	}
+	options := optionValues(sm.settings, settings)
 
-	// check if new engine needs to be loaded
-	if settings["Engine Path"] != sm.gui.eng.Path {
-		// load engine TODO Change these settings
-		newEngine, err := eval.InitializeStockfish(
+	// stop the board's searches so the changes don't wait for them, the
+	// game is evaluated again below with the new settings
+	board := sm.gui.board
+	board.close()
+	defer sm.reloadBoard(board.activeGameID)
+
+	// check if new engines need to be loaded
+	newEngine := sm.gui.eng == nil || settings["Engine Path"] != sm.gui.eng.Path
+	if sm.gui.pool == nil || newEngine || engines != len(sm.gui.pool.Engines) {
+		pool, err := eval.InitializeEnginePool(
+			engines,
 			settings["Engine Path"],
 			settings["SyzygyPath"],
 			moveTime,
@@ -327,31 +401,48 @@ func (sm *settingsMenu) submitSettings() error {
 			hash,
 			multiPV,
 		)
+		sm.gui.setPool(pool, err)
 		if err != nil {
 			return err
 		}
-		sm.gui.eng = newEngine
+		if !newEngine {
+			// the same engine, so the options set for it carry over
+			sm.gui.setOptions(options)
+		}
+		// show the options the engine advertises and save their values, rather
+		// than those of a previous engine
+		sm.settings = newSettingsMenu(sm.gui).settings
+		options = optionValues(sm.settings, nil)
 	} else {
 		// change engine settings
-		err := sm.gui.eng.ChangeOption("SyzygyPath", settings["SyzygyPath"])
-		if err != nil {
-			return err
-		}
-		err = sm.gui.eng.ChangeOption("Threads", settings["Threads"])
+		err := sm.gui.pool.ChangeOption("MoveTime", settings["Movetime"])
 		if err != nil {
 			return err
 		}
-		err = sm.gui.eng.ChangeOption("MoveTime", settings["Movetime"])
+		err = sm.gui.pool.ChangeOption("Depth", settings["Depth"])
 		if err != nil {
 			return err
 		}
-		err = sm.gui.eng.ChangeOption("Hash", settings["Hash"])
-		if err != nil {
-			return err
-		}
-		err = sm.gui.eng.ChangeOption("MultiPV", settings["MultiPV"])
-		if err != nil {
-			return err
+		for _, setting := range sm.settings {
+			if setting.option == nil {
+				continue
+			}
+			value := settings[setting.name]
+			if setting.option.Type == eval.OptionString && value == "" {
+				value = "<empty>"
+			}
+			if setting.option.Type == eval.OptionButton {
+				if value == "" {
+					continue
+				}
+				setting.data = ""
+			} else if value == currentValue(sm.gui.pool, setting.option) {
+				continue
+			}
+			err := sm.gui.pool.ChangeOption(setting.option.Name, value)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -360,10 +451,63 @@ func (sm *settingsMenu) submitSettings() error {
 		EnginePath: settings["Engine Path"],
 		SyzygyPath: settings["SyzygyPath"],
 		Movetime:   moveTime,
+		Depth:      depth,
 		Threads:    threads,
 		Hash:       hash,
 		MultiPV:    multiPV,
+		Engines:    engines,
+		Options:    options,
 	})
 
 	return nil
 }
+
+// Returns the values of the engine's options in the settings, leaving out
+// buttons and the options saved as settings of their own
+//
+// values are the values entered in the menu by name, the settings' own
+// values are used if it's nil
+func optionValues(settings []*setting, values map[string]string) map[string]string {
+	options := map[string]string{}
+	for _, setting := range settings {
+		if setting.option == nil || setting.option.Type == eval.OptionButton {
+			continue
+		}
+		switch setting.name {
+		case "Threads", "Hash", "MultiPV", "SyzygyPath":
+			continue
+		}
+		if values == nil {
+			options[setting.name] = setting.data
+		} else {
+			options[setting.name] = values[setting.name]
+		}
+	}
+	return options
+}
+
+// Returns the value of an option across the pool
+//
+// Threads and Hash are the totals shared between the engines, the other
+// options are set to the same value on each engine
+func currentValue(pool *eval.Pool, option *eval.Option) string {
+	switch option.Name {
+	case "Threads":
+		return fmt.Sprintf("%d", pool.Threads)
+	case "Hash":
+		return fmt.Sprintf("%d", pool.Hash)
+	}
+	return option.Value
+}
+
+// Replaces the board with a new one for the game, evaluated by the current engine
+func (sm *settingsMenu) reloadBoard(gameID int) {
+	if gameID == 0 {
+		return
+	}
+	selectedGame, err := sm.gui.db.GetGameByID(gameID)
+	if err != nil {
+		return
+	}
+	sm.gui.board = newBoard(sm.gui, selectedGame)
+}
@@ -124,6 +124,7 @@ func (s *sidebar) updateState(gtx layout.Context) error {
 				}
 			}
 		}
+		s.gui.board.close()
 		s.gui.board = newBoard(s.gui, &selectedGame)
 	}
 	return nil
@@ -163,6 +164,9 @@ func (gb *gameButton) Layout(gtx layout.Context, th *chessAnalysisTheme, i, widt
 			margins := layout.Inset{Left: unit.Dp(8)}
 			return margins.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 				labelStr := fmt.Sprintf("%d:%s", gb.game.ID, gb.game.ChessdotcomID)
+				if gb.game.ChessdotcomID == "" && (gb.game.White != "" || gb.game.Black != "") {
+					labelStr = fmt.Sprintf("%d:%s vs %s", gb.game.ID, gb.game.White, gb.game.Black)
+				}
 				gameLabel := material.Label(th.giouiTheme, unit.Sp(16), labelStr)
 				gameLabel.Color = th.text
 				gameLabel.Alignment = text.Start
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/LoreviQ/ChessAnalysis/app/internal/game"
 )
 
 // GET /readiness handler
@@ -12,7 +15,9 @@ func (cfg *serverCfg) getReadiness(w http.ResponseWriter, r *http.Request) {
 }
 
 type postMovesRequest struct {
-	Moves []string `json:"moves"`
+	Moves   []string `json:"moves"`
+	FEN     string   `json:"fen"`     // starting position, empty for the initial position
+	Variant string   `json:"variant"` // e.g. "Atomic", empty for standard chess
 }
 
 // POST /games/{id}/moves handler
@@ -34,8 +39,14 @@ func (cfg *serverCfg) postMoves(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	variant, err := game.ParseVariant(request.Variant)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "unknown variant")
+		return
+	}
+
 	// Insert moves into database
-	err = cfg.db.InsertMoves(request.Moves, id, true)
+	err = cfg.db.InsertVariantMoves(request.Moves, variant, request.FEN, id, true)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "error inserting moves into db")
 		return
@@ -70,3 +81,39 @@ func (cfg *serverCfg) getLatestMoves(w http.ResponseWriter, r *http.Request) {
 	// Response
 	respondWithJSON(w, http.StatusOK, getLatestMoveResponse{Moves: movesFromDB.Moves})
 }
+
+type postPGNRequest struct {
+	PGN    string `json:"pgn"`
+	Player string `json:"player"` // name of the user in the White and Black tags
+}
+
+type postPGNResponse struct {
+	IDs []int `json:"ids"`
+}
+
+// POST /games/pgn handler
+//
+// This handler is used to import every game in a PGN into the database.
+func (cfg *serverCfg) postPGN(w http.ResponseWriter, r *http.Request) {
+	// Decode request
+	var request postPGNRequest
+	err := decodeRequest(w, r, &request)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "failed to decode request body")
+		return
+	}
+
+	// Insert games into database
+	ids, err := cfg.db.InsertPGN(request.PGN, request.Player)
+	if errors.Is(err, game.ErrInvalidPGN) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error inserting games into db")
+		return
+	}
+
+	// Response
+	respondWithJSON(w, http.StatusCreated, postPGNResponse{IDs: ids})
+}
@@ -22,6 +22,7 @@ func NewServer(db *database.Database) (*http.Server, serverCfg) {
 	mux.HandleFunc("GET /readiness", cfg.getReadiness)
 	mux.HandleFunc("POST /games/{id}/moves", cfg.postMoves)
 	mux.HandleFunc("GET /games/{id}/moves/latest", cfg.getLatestMoves)
+	mux.HandleFunc("POST /games/pgn", cfg.postPGN)
 	return &http.Server{
 		Addr:    cfg.url.Host,
 		Handler: CorsMiddleware(mux),
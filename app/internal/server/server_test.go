@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -139,6 +140,64 @@ func TestPostGetMoves(t *testing.T) {
 	}
 }
 
+func TestPostPGN(t *testing.T) {
+	// Change the working directory to the root of the project
+	restore := changeDirectoryToRoot()
+	defer restore()
+
+	// Create db connection
+	db, err := database.NewConnection(7)
+	if err != nil {
+		t.Errorf("Error creating database connection: %v", err)
+	}
+	defer db.Close()
+
+	// Create a new server
+	srv, cfg := NewServer(db)
+	go srv.ListenAndServe()
+	defer srv.Close()
+	url := cfg.url.String()
+
+	waitForServerToStart(url)
+
+	tests := []struct {
+		pgn        string
+		statusCode int
+		ids        []int
+	}{
+		{"[White \"A\"]\n[Black \"B\"]\n\n1. e4 e5 2. Nf3 1-0\n\n1. d4 d5 *", http.StatusCreated, []int{1, 2}},
+		{"1. e4 e5 2. Ke3 *", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		body, err := json.Marshal(map[string]string{"pgn": tt.pgn})
+		if err != nil {
+			t.Errorf("Error marshalling request body: %v", err)
+		}
+		resp, err := http.Post(
+			fmt.Sprintf("%s/games/pgn", url),
+			"application/json",
+			strings.NewReader(string(body)),
+		)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("Expected status code %d, got %d", tt.statusCode, resp.StatusCode)
+		}
+		if tt.ids != nil {
+			var response postPGNResponse
+			err = json.NewDecoder(resp.Body).Decode(&response)
+			if err != nil {
+				t.Errorf("Error decoding response: %v", err)
+			}
+			if !reflect.DeepEqual(response.IDs, tt.ids) {
+				t.Errorf("Expected ids %v, got %v", tt.ids, response.IDs)
+			}
+		}
+		resp.Body.Close()
+	}
+}
+
 // blocking function that waits for the server to start
 func waitForServerToStart(url string) {
 	for {
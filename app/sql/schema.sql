@@ -3,7 +3,16 @@ CREATE TABLE IF NOT EXISTS games (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     created_at TEXT DEFAULT CURRENT_TIMESTAMP,
     chessdotcom_id TEXT UNIQUE,
-    playerIsWhite BOOLEAN NOT NULL
+    playerIsWhite BOOLEAN NOT NULL,
+    event TEXT,
+    site TEXT,
+    date TEXT,
+    round TEXT,
+    white TEXT,
+    black TEXT,
+    result TEXT,
+    fen TEXT,
+    variant TEXT
 );
 
 CREATE TABLE IF NOT EXISTS moves (
@@ -13,5 +22,8 @@ CREATE TABLE IF NOT EXISTS moves (
     created_at TEXT DEFAULT CURRENT_TIMESTAMP,
     scores TEXT,
     depth INTEGER,
+    best_lines TEXT,
+    tree TEXT,
+    wdl TEXT,
     FOREIGN KEY (game_id) REFERENCES games(id)
 );
\ No newline at end of file